@@ -0,0 +1,957 @@
+package parsesvg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/timdrysdale/geo"
+	"github.com/timdrysdale/unipdf/v3/core"
+	"github.com/timdrysdale/unipdf/v3/creator"
+	"github.com/timdrysdale/unipdf/v3/model"
+)
+
+// VectorChrome is the chrome layer of a ladder, turned into pdf drawing
+// operations, so that it can be drawn instead of an exported image of it.
+// Items are in document order, so later items are drawn on top.
+type VectorChrome struct {
+	Dim   geo.Dim      `json:"dim"`
+	Items []ChromeItem `json:"items"`
+}
+
+// ChromeItem is either a run of shapes, as a pdf content stream with its
+// origin at the bottom left of the ladder, in points, or a line of text
+type ChromeItem struct {
+	Content   string             `json:"content,omitempty"`
+	Opacities map[string]Opacity `json:"opacities,omitempty"` // graphics states used in Content, by name
+	Text      *ChromeText        `json:"text,omitempty"`
+}
+
+// Opacity is the alpha for filling and stroking, from 0 (clear) to 1
+type Opacity struct {
+	Fill   float64 `json:"fill"`
+	Stroke float64 `json:"stroke"`
+}
+
+// ChromeText is a line of text, positioned by the start of its baseline,
+// measured in points from the top left of the ladder, as textfields are
+type ChromeText struct {
+	Text     string     `json:"text"`
+	Position geo.Point  `json:"position"`
+	Anchor   string     `json:"anchor"` // start, middle or end, as per text-anchor
+	Font     string     `json:"font"`   // one of the standard 14 pdf fonts
+	FontSize float64    `json:"fontSize"`
+	Color    [3]float64 `json:"color"`
+}
+
+// svgNode keeps every element, in document order, which the typed structs
+// don't, because painting order matters for chrome
+type svgNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []svgNode  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+func (n *svgNode) attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local && a.Name.Space == "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *svgNode) inkscapeAttr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local && a.Name.Space == inkscapeNamespace {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// DefineVectorChromeFromSVG reads the shapes and text on the chrome layer
+// of a ladder, including those in groups and links. Rects, circles,
+// ellipses, lines, polylines, polygons, paths and text are drawn, with
+// their fill, stroke and opacity. Gradients, patterns, markers, clipping,
+// filters, clones and embedded images are not, and are logged.
+func DefineVectorChromeFromSVG(input []byte) (*VectorChrome, error) {
+
+	svg, err := ParseSvgWithOptions(input, DefaultParseOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	dim, err := getLadderDim(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	sf, err := getUserUnitTransform(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	var root svgNode
+
+	err = xml.Unmarshal(input, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &chromeBuilder{chrome: &VectorChrome{Dim: dim}, opacities: make(map[Opacity]string)}
+
+	// pdf has its origin at the bottom left, so flip the page over
+	flip := Matrix{1, 0, 0, -1, 0, dim.Height}
+
+	for _, child := range root.Children {
+		if child.XMLName.Local != "g" || child.XMLName.Space != svgNamespace {
+			continue
+		}
+		// top level groups are layers, as per getLayerGroups
+		err = b.walk(&child, child.inkscapeAttr("label"), true, sf, flip, chromeStyle{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b.endRun()
+
+	return b.chrome, nil
+}
+
+// Draw puts the chrome on the current page, with the top left corner of
+// the ladder at corner
+func (v *VectorChrome) Draw(c *creator.Creator, corner geo.Point) error {
+
+	for _, item := range v.Items {
+
+		if item.Text != nil {
+			err := item.Text.draw(c, corner)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		block, err := item.block(v.Dim)
+		if err != nil {
+			return err
+		}
+
+		block.SetPos(corner.X, corner.Y)
+
+		err = c.Draw(block)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// block wraps the content in a page the size of the ladder, because that
+// is how the creator takes content streams with their own resources
+func (item ChromeItem) block(dim geo.Dim) (*creator.Block, error) {
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: dim.Width, Ury: dim.Height}
+
+	if page.Resources == nil { //avoid seg fault, obvs
+		page.Resources = model.NewPdfPageResources()
+	}
+
+	var names []string
+	for name := range item.Opacities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		gs := core.MakeDict()
+		gs.Set("ca", core.MakeFloat(item.Opacities[name].Fill))
+		gs.Set("CA", core.MakeFloat(item.Opacities[name].Stroke))
+		err := page.Resources.AddExtGState(core.PdfObjectName(name), gs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := page.AddContentStreamByString(item.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return creator.NewBlockFromPage(page)
+}
+
+// chromeTextWidth is the box that anchored text is aligned in, which only
+// needs to be wide enough not to wrap
+const chromeTextWidth = 1000.0
+
+func (t *ChromeText) draw(c *creator.Creator, corner geo.Point) error {
+
+	font, err := model.NewStandard14Font(model.StdFontName(t.Font))
+	if err != nil {
+		return err
+	}
+
+	p := c.NewParagraph(t.Text)
+	p.SetFont(font)
+	p.SetFontSize(t.FontSize)
+	p.SetColor(creator.ColorRGBFromArithmetic(t.Color[0], t.Color[1], t.Color[2]))
+
+	// a paragraph is positioned by its top, a font size above the baseline
+	x := corner.X + t.Position.X
+	y := corner.Y + t.Position.Y - t.FontSize
+
+	switch t.Anchor {
+	case "middle":
+		p.SetWidth(chromeTextWidth)
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		x = x - chromeTextWidth/2
+	case "end":
+		p.SetWidth(chromeTextWidth)
+		p.SetTextAlignment(creator.TextAlignmentRight)
+		x = x - chromeTextWidth
+	default:
+		p.SetEnableWrap(false)
+	}
+
+	p.SetPos(x, y)
+
+	return c.Draw(p)
+}
+
+// chromeStyle holds the presentation properties in force for an element
+type chromeStyle map[string]string
+
+// properties that children get from their parents
+var inheritedProperties = []string{
+	"fill", "fill-opacity", "fill-rule",
+	"stroke", "stroke-width", "stroke-opacity", "stroke-linecap", "stroke-linejoin",
+	"stroke-miterlimit", "stroke-dasharray", "stroke-dashoffset",
+	"font-size", "font-family", "font-weight", "font-style", "text-anchor",
+	"visibility",
+}
+
+var presentationAttributes = append([]string{"opacity", "display"}, inheritedProperties...)
+
+// cascade works out the style of n, from its parent's, its presentation
+// attributes, then its style attribute, which wins. Opacity isn't
+// inherited, but a group's opacity applies to everything in it, so it is
+// multiplied down instead.
+func (parent chromeStyle) cascade(n *svgNode) chromeStyle {
+
+	style := chromeStyle{}
+
+	for _, p := range inheritedProperties {
+		if v, ok := parent[p]; ok {
+			style[p] = v
+		}
+	}
+
+	own := chromeStyle{}
+
+	for _, p := range presentationAttributes {
+		if v := n.attr(p); v != "" {
+			own[p] = v
+		}
+	}
+
+	for _, declaration := range strings.Split(n.attr("style"), ";") {
+		kv := strings.SplitN(declaration, ":", 2)
+		if len(kv) == 2 {
+			own[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	for k, v := range own {
+		if k != "opacity" {
+			style[k] = v
+		}
+	}
+
+	opacity := style.number(parent, "opacity", 1) * style.number(own, "opacity", 1)
+	style["opacity"] = strconv.FormatFloat(opacity, 'f', -1, 64)
+
+	return style
+}
+
+// number reads a unitless property from from, clamped to 0..1 if it is an
+// opacity, defaulting if it is missing or can't be read
+func (style chromeStyle) number(from chromeStyle, property string, def float64) float64 {
+
+	v, ok := from[property]
+	if !ok {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil {
+		return def
+	}
+
+	if strings.HasSuffix(v, "%") {
+		f = f / 100
+	}
+
+	if strings.HasSuffix(property, "opacity") {
+		f = math.Max(0, math.Min(1, f))
+	}
+
+	return f
+}
+
+// length reads a property with units into user units
+func (style chromeStyle) length(property string, def float64) float64 {
+
+	v, ok := style[property]
+	if !ok {
+		return def
+	}
+
+	length, err := ParseLength(v)
+	if err != nil {
+		return def
+	}
+
+	f, err := length.UserUnits()
+	if err != nil {
+		return def
+	}
+
+	return f
+}
+
+type chromeBuilder struct {
+	chrome    *VectorChrome
+	content   strings.Builder
+	opacities map[Opacity]string // graphics state name for each opacity
+	used      map[string]Opacity // graphics states used in the current run
+}
+
+// endRun finishes the shapes drawn since the last text
+func (b *chromeBuilder) endRun() {
+
+	if b.content.Len() == 0 {
+		return
+	}
+
+	b.chrome.Items = append(b.chrome.Items, ChromeItem{Content: b.content.String(), Opacities: b.used})
+	b.content.Reset()
+	b.used = nil
+}
+
+// walk draws n and its children, if they are on the chrome layer. toPoints
+// maps user units to points from the top left of the ladder, and flip
+// then turns that into pdf coordinates. The chrome layer itself is drawn
+// even if it is hidden, as it may be while designing the form elements.
+func (b *chromeBuilder) walk(n *svgNode, layer string, isLayer bool, toPoints, flip Matrix, parent chromeStyle) error {
+
+	if n.XMLName.Space != svgNamespace {
+		return nil
+	}
+
+	// links are groups as far as drawing goes, as inkscape makes them
+	// around whatever is linked
+	isGroup := n.XMLName.Local == "g" || n.XMLName.Local == "a"
+
+	if !isGroup && layer != ChromeLayer {
+		return nil
+	}
+
+	id := n.attr("id")
+
+	own, err := ParseTransform(n.attr("transform"))
+	if err != nil {
+		return &ElementError{Layer: layer, ID: id, Attribute: "transform", Value: n.attr("transform"), Err: err}
+	}
+
+	m := toPoints.Multiply(own)
+
+	style := parent.cascade(n)
+
+	if style["display"] == "none" && !(isLayer && layer == ChromeLayer) {
+		return nil
+	}
+
+	switch n.XMLName.Local {
+
+	case "g", "a":
+		for _, child := range n.Children {
+
+			childLayer := layer
+			childIsLayer := child.inkscapeAttr("groupmode") == "layer"
+			if childIsLayer {
+				childLayer = child.inkscapeAttr("label")
+			}
+
+			err := b.walk(&child, childLayer, childIsLayer, m, flip, style)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "text":
+		return b.text(n, m, style)
+
+	case "title", "desc", "metadata", "defs":
+		return nil // nothing to see
+
+	case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+		// drawn below
+
+	default:
+		log.Errorf("Can't draw <%s> %s in vector chrome, so leaving it out\n", n.XMLName.Local, id)
+		return nil
+	}
+
+	commands, isLine, err := shapeCommands(n)
+	if err != nil {
+		return elementError(err, layer, id, nil)
+	}
+
+	if len(commands) == 0 {
+		return nil // not a shape we draw, or has no area
+	}
+
+	b.shape(commands, isLine, flip.Multiply(m), style)
+
+	return nil
+}
+
+// shapeCommands gives the outline of a basic shape as path commands, in
+// its own user units. isLine is set for shapes that can't be filled.
+func shapeCommands(n *svgNode) ([]pathCommand, bool, error) {
+
+	id := n.attr("id")
+
+	number := func(attribute string) (float64, error) {
+		return parseUserUnits(id, attribute, n.attr(attribute), true)
+	}
+
+	switch n.XMLName.Local {
+
+	case "path":
+		commands, err := parsePathData(n.attr("d"))
+		if err != nil {
+			return nil, false, attributeError(id, "d", n.attr("d"), err)
+		}
+		return commands, false, nil
+
+	case "rect":
+		var v [6]float64
+		for i, a := range []string{"x", "y", "width", "height", "rx", "ry"} {
+			var err error
+			v[i], err = number(a)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		// one missing radius is the same as the other
+		rx, ry := v[4], v[5]
+		if n.attr("rx") == "" {
+			rx = ry
+		}
+		if n.attr("ry") == "" {
+			ry = rx
+		}
+		return rectCommands(v[0], v[1], v[2], v[3], rx, ry), false, nil
+
+	case "circle", "ellipse":
+		var v [4]float64
+		names := []string{"cx", "cy", "rx", "ry"}
+		if n.XMLName.Local == "circle" {
+			names = []string{"cx", "cy", "r", "r"}
+		}
+		for i, a := range names {
+			var err error
+			v[i], err = number(a)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		return ellipseCommands(v[0], v[1], v[2], v[3]), false, nil
+
+	case "line":
+		var v [4]float64
+		for i, a := range []string{"x1", "y1", "x2", "y2"} {
+			var err error
+			v[i], err = number(a)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		return []pathCommand{
+			{Op: 'M', Points: []geo.Point{{X: v[0], Y: v[1]}}},
+			{Op: 'L', Points: []geo.Point{{X: v[2], Y: v[3]}}},
+		}, true, nil
+
+	case "polyline", "polygon":
+		s := pathScanner{str: n.attr("points")}
+		var commands []pathCommand
+		for s.numberNext() {
+			x, err := s.number()
+			if err != nil {
+				return nil, false, attributeError(id, "points", n.attr("points"), err)
+			}
+			y, err := s.number()
+			if err != nil {
+				return nil, false, attributeError(id, "points", n.attr("points"), err)
+			}
+			op := byte('L')
+			if len(commands) == 0 {
+				op = 'M'
+			}
+			commands = append(commands, pathCommand{Op: op, Points: []geo.Point{{X: x, Y: y}}})
+		}
+		if n.XMLName.Local == "polygon" && len(commands) > 0 {
+			commands = append(commands, pathCommand{Op: 'Z'})
+		}
+		return commands, false, nil
+	}
+
+	return nil, false, nil
+}
+
+// the distance of the control points along the tangent, as a fraction of
+// the radius, for a quarter ellipse
+const kappa = 0.5522847498307936
+
+func ellipseCommands(cx, cy, rx, ry float64) []pathCommand {
+
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+
+	kx, ky := kappa*rx, kappa*ry
+
+	return []pathCommand{
+		{Op: 'M', Points: []geo.Point{{X: cx + rx, Y: cy}}},
+		{Op: 'C', Points: []geo.Point{{X: cx + rx, Y: cy + ky}, {X: cx + kx, Y: cy + ry}, {X: cx, Y: cy + ry}}},
+		{Op: 'C', Points: []geo.Point{{X: cx - kx, Y: cy + ry}, {X: cx - rx, Y: cy + ky}, {X: cx - rx, Y: cy}}},
+		{Op: 'C', Points: []geo.Point{{X: cx - rx, Y: cy - ky}, {X: cx - kx, Y: cy - ry}, {X: cx, Y: cy - ry}}},
+		{Op: 'C', Points: []geo.Point{{X: cx + kx, Y: cy - ry}, {X: cx + rx, Y: cy - ky}, {X: cx + rx, Y: cy}}},
+		{Op: 'Z'},
+	}
+}
+
+func rectCommands(x, y, w, h, rx, ry float64) []pathCommand {
+
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+
+	rx = math.Max(0, math.Min(rx, w/2))
+	ry = math.Max(0, math.Min(ry, h/2))
+
+	if rx == 0 || ry == 0 {
+		return []pathCommand{
+			{Op: 'M', Points: []geo.Point{{X: x, Y: y}}},
+			{Op: 'L', Points: []geo.Point{{X: x + w, Y: y}}},
+			{Op: 'L', Points: []geo.Point{{X: x + w, Y: y + h}}},
+			{Op: 'L', Points: []geo.Point{{X: x, Y: y + h}}},
+			{Op: 'Z'},
+		}
+	}
+
+	kx, ky := kappa*rx, kappa*ry
+
+	return []pathCommand{
+		{Op: 'M', Points: []geo.Point{{X: x + rx, Y: y}}},
+		{Op: 'L', Points: []geo.Point{{X: x + w - rx, Y: y}}},
+		{Op: 'C', Points: []geo.Point{{X: x + w - rx + kx, Y: y}, {X: x + w, Y: y + ry - ky}, {X: x + w, Y: y + ry}}},
+		{Op: 'L', Points: []geo.Point{{X: x + w, Y: y + h - ry}}},
+		{Op: 'C', Points: []geo.Point{{X: x + w, Y: y + h - ry + ky}, {X: x + w - rx + kx, Y: y + h}, {X: x + w - rx, Y: y + h}}},
+		{Op: 'L', Points: []geo.Point{{X: x + rx, Y: y + h}}},
+		{Op: 'C', Points: []geo.Point{{X: x + rx - kx, Y: y + h}, {X: x, Y: y + h - ry + ky}, {X: x, Y: y + h - ry}}},
+		{Op: 'L', Points: []geo.Point{{X: x, Y: y + ry}}},
+		{Op: 'C', Points: []geo.Point{{X: x, Y: y + ry - ky}, {X: x + rx - kx, Y: y}, {X: x + rx, Y: y}}},
+		{Op: 'Z'},
+	}
+}
+
+// shape writes the operators to paint the commands. The transform is set
+// with cm, so the path stays in user units and the stroke width scales
+// with it, as it does in the svg.
+func (b *chromeBuilder) shape(commands []pathCommand, isLine bool, m Matrix, style chromeStyle) {
+
+	if v := style["visibility"]; v == "hidden" || v == "collapse" {
+		return
+	}
+
+	fill, fillOK := parsePaint(style, "fill", "#000000")
+	stroke, strokeOK := parsePaint(style, "stroke", "none")
+
+	if isLine {
+		fillOK = false
+	}
+
+	if !fillOK && !strokeOK {
+		return
+	}
+
+	var ops []string
+	num := pdfNumber
+
+	ops = append(ops, "q", fmt.Sprintf("%s %s %s %s %s %s cm", num(m[0]), num(m[1]), num(m[2]), num(m[3]), num(m[4]), num(m[5])))
+
+	opacity := style.number(style, "opacity", 1)
+	alpha := Opacity{
+		Fill:   opacity * style.number(style, "fill-opacity", 1),
+		Stroke: opacity * style.number(style, "stroke-opacity", 1),
+	}
+	if alpha != (Opacity{1, 1}) {
+		ops = append(ops, fmt.Sprintf("/%s gs", b.graphicsState(alpha)))
+	}
+
+	if fillOK {
+		ops = append(ops, fmt.Sprintf("%s %s %s rg", num(fill[0]), num(fill[1]), num(fill[2])))
+	}
+
+	if strokeOK {
+		ops = append(ops, fmt.Sprintf("%s %s %s RG", num(stroke[0]), num(stroke[1]), num(stroke[2])))
+		ops = append(ops, fmt.Sprintf("%s w", num(style.length("stroke-width", 1))))
+		ops = append(ops, fmt.Sprintf("%d J", lineStyleIndex(style["stroke-linecap"], "butt", "round", "square")))
+		ops = append(ops, fmt.Sprintf("%d j", lineStyleIndex(style["stroke-linejoin"], "miter", "round", "bevel")))
+		ops = append(ops, fmt.Sprintf("%s M", num(style.number(style, "stroke-miterlimit", 4))))
+		if dashes := dashArray(style["stroke-dasharray"]); len(dashes) > 0 {
+			ops = append(ops, fmt.Sprintf("[%s] %s d", strings.Join(dashes, " "), num(style.length("stroke-dashoffset", 0))))
+		}
+	}
+
+	for _, c := range commands {
+		switch c.Op {
+		case 'M':
+			ops = append(ops, fmt.Sprintf("%s %s m", num(c.Points[0].X), num(c.Points[0].Y)))
+		case 'L':
+			ops = append(ops, fmt.Sprintf("%s %s l", num(c.Points[0].X), num(c.Points[0].Y)))
+		case 'C':
+			ops = append(ops, fmt.Sprintf("%s %s %s %s %s %s c",
+				num(c.Points[0].X), num(c.Points[0].Y),
+				num(c.Points[1].X), num(c.Points[1].Y),
+				num(c.Points[2].X), num(c.Points[2].Y)))
+		case 'Z':
+			ops = append(ops, "h")
+		}
+	}
+
+	evenOdd := style["fill-rule"] == "evenodd"
+
+	switch {
+	case fillOK && strokeOK && evenOdd:
+		ops = append(ops, "B*")
+	case fillOK && strokeOK:
+		ops = append(ops, "B")
+	case fillOK && evenOdd:
+		ops = append(ops, "f*")
+	case fillOK:
+		ops = append(ops, "f")
+	default:
+		ops = append(ops, "S")
+	}
+
+	ops = append(ops, "Q")
+
+	b.content.WriteString(strings.Join(ops, "\n"))
+	b.content.WriteString("\n")
+}
+
+// graphicsState names the graphics state for an opacity, reusing names so
+// each opacity only needs one resource
+func (b *chromeBuilder) graphicsState(alpha Opacity) string {
+
+	name, ok := b.opacities[alpha]
+	if !ok {
+		name = fmt.Sprintf("GSchrome%d", len(b.opacities))
+		b.opacities[alpha] = name
+	}
+
+	if b.used == nil {
+		b.used = make(map[string]Opacity)
+	}
+	b.used[name] = alpha
+
+	return name
+}
+
+// text adds a line of text for each tspan with its own position, or for
+// the text itself if it has none. Tspans without a position carry on the
+// line before them, in its style, because there is no measuring text here.
+func (b *chromeBuilder) text(n *svgNode, m Matrix, style chromeStyle) error {
+
+	if v := style["visibility"]; v == "hidden" || v == "collapse" {
+		return nil
+	}
+
+	b.endRun()
+
+	x, err := parseUserUnits(n.attr("id"), "x", firstCoordinate(n.attr("x")), true)
+	if err != nil {
+		return elementError(err, ChromeLayer, n.attr("id"), nil)
+	}
+	y, err := parseUserUnits(n.attr("id"), "y", firstCoordinate(n.attr("y")), true)
+	if err != nil {
+		return elementError(err, ChromeLayer, n.attr("id"), nil)
+	}
+
+	var current *ChromeText
+
+	add := func(str string, at geo.Point, style chromeStyle, positioned bool) {
+
+		str = strings.Join(strings.Fields(str), " ")
+		if str == "" {
+			return
+		}
+
+		if current != nil && !positioned {
+			current.Text = current.Text + " " + str
+			return
+		}
+
+		color, ok := parsePaint(style, "fill", "#000000")
+		if !ok {
+			current = nil
+			return // stroked text isn't supported
+		}
+
+		// font sizes scale with the transform, but can't be skewed
+		scale := math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+
+		current = &ChromeText{
+			Text:     str,
+			Position: m.Apply(at),
+			Anchor:   style["text-anchor"],
+			Font:     chromeFont(style),
+			FontSize: style.length("font-size", 16) * scale,
+			Color:    color,
+		}
+		b.chrome.Items = append(b.chrome.Items, ChromeItem{Text: current})
+	}
+
+	if len(n.Children) == 0 {
+		add(n.Text, geo.Point{X: x, Y: y}, style, true)
+		return nil
+	}
+
+	for _, child := range n.Children {
+
+		if child.XMLName.Local != "tspan" {
+			continue
+		}
+
+		childStyle := style.cascade(&child)
+		at := geo.Point{X: x, Y: y}
+		positioned := child.attr("x") != "" || child.attr("y") != ""
+
+		if child.attr("x") != "" {
+			at.X, err = parseUserUnits(child.attr("id"), "x", firstCoordinate(child.attr("x")), true)
+			if err != nil {
+				return elementError(err, ChromeLayer, child.attr("id"), nil)
+			}
+		}
+		if child.attr("y") != "" {
+			at.Y, err = parseUserUnits(child.attr("id"), "y", firstCoordinate(child.attr("y")), true)
+			if err != nil {
+				return elementError(err, ChromeLayer, child.attr("id"), nil)
+			}
+		}
+
+		add(child.Text, at, childStyle, positioned)
+	}
+
+	return nil
+}
+
+// text x and y can be a list, one per character, but we only place lines
+func firstCoordinate(list string) string {
+	fields := strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// chromeFont picks the closest of the standard 14 pdf fonts, which any pdf
+// viewer has, rather than embedding the font the designer used
+func chromeFont(style chromeStyle) string {
+
+	family := strings.ToLower(style["font-family"])
+
+	weight := style["font-weight"]
+	bold := weight == "bold" || weight == "bolder"
+	if w, err := strconv.Atoi(weight); err == nil && w >= 600 {
+		bold = true
+	}
+
+	italic := style["font-style"] == "italic" || style["font-style"] == "oblique"
+
+	switch {
+	case strings.Contains(family, "courier") || strings.Contains(family, "mono"):
+		return fontVariant("Courier", "Courier", "-Bold", "-Oblique", bold, italic)
+	case strings.Contains(family, "times") || (strings.Contains(family, "serif") && !strings.Contains(family, "sans")):
+		return fontVariant("Times", "Times-Roman", "-Bold", "-Italic", bold, italic)
+	}
+
+	return fontVariant("Helvetica", "Helvetica", "-Bold", "-Oblique", bold, italic)
+}
+
+func fontVariant(family, regular, bold, italic string, isBold, isItalic bool) string {
+	switch {
+	case isBold && isItalic:
+		return family + bold + strings.TrimPrefix(italic, "-")
+	case isBold:
+		return family + bold
+	case isItalic:
+		return family + italic
+	}
+	return regular
+}
+
+var namedColors = map[string]string{
+	"black":   "#000000",
+	"white":   "#ffffff",
+	"red":     "#ff0000",
+	"green":   "#008000",
+	"lime":    "#00ff00",
+	"blue":    "#0000ff",
+	"yellow":  "#ffff00",
+	"cyan":    "#00ffff",
+	"magenta": "#ff00ff",
+	"grey":    "#808080",
+	"gray":    "#808080",
+	"silver":  "#c0c0c0",
+	"maroon":  "#800000",
+	"navy":    "#000080",
+	"orange":  "#ffa500",
+	"purple":  "#800080",
+	"teal":    "#008080",
+	"olive":   "#808000",
+}
+
+// parsePaint reads a fill or stroke colour as rgb from 0 to 1. ok is false
+// if nothing should be painted, including for gradients and patterns,
+// which aren't supported.
+func parsePaint(style chromeStyle, property, def string) ([3]float64, bool) {
+
+	var rgb [3]float64
+
+	paint, set := style[property]
+	if !set {
+		paint = def
+	}
+
+	paint = strings.ToLower(strings.TrimSpace(paint))
+
+	if named, ok := namedColors[paint]; ok {
+		paint = named
+	}
+
+	switch {
+
+	case paint == "none" || paint == "transparent":
+		return rgb, false
+
+	case strings.HasPrefix(paint, "#"):
+		hex := paint[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			break
+		}
+		for i := range rgb {
+			v, err := strconv.ParseUint(hex[2*i:2*i+2], 16, 8)
+			if err != nil {
+				return rgb, false
+			}
+			rgb[i] = float64(v) / 255
+		}
+		return rgb, true
+
+	case strings.HasPrefix(paint, "rgb(") && strings.HasSuffix(paint, ")"):
+		parts := strings.Split(paint[4:len(paint)-1], ",")
+		if len(parts) != 3 {
+			break
+		}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			scale := 255.0
+			if strings.HasSuffix(part, "%") {
+				part = strings.TrimSuffix(part, "%")
+				scale = 100
+			}
+			v, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return rgb, false
+			}
+			rgb[i] = math.Max(0, math.Min(1, v/scale))
+		}
+		return rgb, true
+	}
+
+	log.Errorf("Can't draw %s %q in vector chrome, so leaving it out\n", property, paint)
+
+	return rgb, false
+}
+
+func lineStyleIndex(value string, names ...string) int {
+	for i, name := range names {
+		if value == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// dashArray reads stroke-dasharray into user units, or nothing for none.
+// An odd number of dashes is repeated to make it even, as per svg.
+func dashArray(value string) []string {
+
+	var dashes []string
+
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+
+		length, err := ParseLength(field)
+		if err != nil {
+			return nil // including none
+		}
+
+		v, err := length.UserUnits()
+		if err != nil || v < 0 {
+			return nil
+		}
+
+		dashes = append(dashes, pdfNumber(v))
+	}
+
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes, dashes...)
+	}
+
+	return dashes
+}
+
+// pdfNumber writes a number without an exponent, which pdf doesn't allow
+func pdfNumber(v float64) string {
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		v = 0
+	}
+
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+
+	if s == "-0" || s == "" {
+		return "0"
+	}
+
+	return s
+}
@@ -0,0 +1,201 @@
+package parsesvg
+
+import (
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chromePage has two user units to a point
+var chromePage = fixturePage{Width: "100pt", Height: "50pt", ViewBox: "0 0 200 100", Units: "pt"}
+
+var chromeSVG = inkscapeSVG(chromePage, `
+  <g
+     inkscape:label="chrome"
+     inkscape:groupmode="layer"
+     style="display:none"
+     id="layer1">
+    <rect
+       id="rect1"
+       x="0"
+       y="0"
+       width="20"
+       height="10"
+       style="fill:#ff0000;stroke:none" />
+    <text
+       id="text1"
+       x="100"
+       y="50"
+       style="font-size:20px;font-family:serif;font-weight:bold;text-anchor:middle;fill:blue"><tspan
+         id="tspan1"
+         x="100"
+         y="50">Total</tspan></text>
+    <g
+       id="g1"
+       style="opacity:0.5"
+       transform="translate(10,10)">
+      <circle
+         id="circle1"
+         cx="0"
+         cy="0"
+         r="5"
+         style="fill:none;stroke:#000;stroke-width:2;stroke-opacity:0.5" />
+      <ellipse
+         id="ellipse1"
+         cx="0"
+         cy="0"
+         rx="5"
+         ry="2"
+         style="display:none" />
+    </g>
+  </g>
+  <g
+     inkscape:label="textfields"
+     inkscape:groupmode="layer"
+     id="layer2">
+    <rect
+       id="rect2"
+       x="0"
+       y="0"
+       width="20"
+       height="10" />
+  </g>
+`)
+
+func TestDefineVectorChrome(t *testing.T) {
+
+	chrome, err := DefineVectorChromeFromSVG([]byte(chromeSVG))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chrome.Items) != 3 {
+		t.Fatalf("Expected rect, text, circle, got %v", chrome.Items)
+	}
+
+	// half a point per user unit, flipped so the origin is the bottom left
+	rect := chrome.Items[0].Content
+	for _, op := range []string{"0.5 0 0 -0.5 0 50 cm", "1 0 0 rg", "0 0 m", "20 0 l", "h\nf\nQ"} {
+		if !strings.Contains(rect, op) {
+			t.Errorf("Expected %q in rect\n%s", op, rect)
+		}
+	}
+
+	want := ChromeText{
+		Text:     "Total",
+		Anchor:   "middle",
+		Font:     "Times-Bold",
+		FontSize: 10,
+		Color:    [3]float64{0, 0, 1},
+	}
+	got := *chrome.Items[1].Text
+	if got.Position.X != 50 || got.Position.Y != 25 {
+		t.Errorf("Text position wrong %v", got.Position)
+	}
+	got.Position = want.Position
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Text wrong\n%v\n%v", want, got)
+	}
+
+	// group opacity multiplies with the stroke's own, and the hidden
+	// ellipse is left out
+	circle := chrome.Items[2]
+	if !reflect.DeepEqual(circle.Opacities, map[string]Opacity{"GSchrome0": {Fill: 0.5, Stroke: 0.25}}) {
+		t.Errorf("Opacities wrong %v", circle.Opacities)
+	}
+	for _, op := range []string{"/GSchrome0 gs", "0 0 0 RG", "2 w", " c\n", "S\nQ"} {
+		if !strings.Contains(circle.Content, op) {
+			t.Errorf("Expected %q in circle\n%s", op, circle.Content)
+		}
+	}
+	if strings.Count(circle.Content, " cm\n") != 1 {
+		t.Errorf("Expected only the circle to be drawn\n%s", circle.Content)
+	}
+}
+
+func TestDefineVectorChromeSidebar(t *testing.T) {
+
+	svgBytes, err := ioutil.ReadFile("./test/sidebar-312pt-mark-ladder.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chrome, err := DefineVectorChromeFromSVG(svgBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	for _, item := range chrome.Items {
+		if item.Text != nil {
+			texts = append(texts, item.Text.Text)
+			if item.Text.Anchor != "middle" || item.Text.Font != "Helvetica" {
+				t.Errorf("Text style wrong %v", item.Text)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(texts, []string{"Sub-", "total"}) {
+		t.Errorf("Text wrong %v", texts)
+	}
+
+	ladder, err := DefineLadderFromSVG(svgBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(chrome.Dim.Width-ladder.Dim.Width) > 1e-9 || math.Abs(chrome.Dim.Height-ladder.Dim.Height) > 1e-9 {
+		t.Errorf("Chrome size %v doesn't match ladder %v", chrome.Dim, ladder.Dim)
+	}
+}
+
+var chromeLinksSVG = inkscapeSVG(chromePage, `
+  <g
+     inkscape:label="chrome"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <a
+       id="a1"
+       xlink:href="https://example.com"
+       transform="translate(5,0)">
+      <g
+         id="g1"
+         transform="translate(0,5)">
+        <rect
+           id="rect1"
+           x="0"
+           y="0"
+           width="20"
+           height="10"
+           style="fill:#ff0000" />
+      </g>
+    </a>
+    <image
+       id="image1"
+       x="0"
+       y="0"
+       width="10"
+       height="10"
+       xlink:href="logo.png" />
+  </g>
+`)
+
+func TestDefineVectorChromeLinks(t *testing.T) {
+
+	chrome, err := DefineVectorChromeFromSVG([]byte(chromeLinksSVG))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the image can't be drawn, so only the rect in the link is
+	if len(chrome.Items) != 1 {
+		t.Fatalf("Expected the rect, got %v", chrome.Items)
+	}
+
+	// moved by both the link and the group, then scaled and flipped
+	if content := chrome.Items[0].Content; !strings.Contains(content, "0.5 0 0 -0.5 2.5 47.5 cm") {
+		t.Errorf("Expected the rect to be moved by the link and group\n%s", content)
+	}
+}
@@ -0,0 +1,220 @@
+package parsesvg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ParseOptions control what ParseSvgWithOptions will accept. A zero limit
+// means no limit.
+type ParseOptions struct {
+	// Strict rejects elements that the parser would otherwise silently
+	// ignore, in the layers that it reads, e.g. an <ellipse> on the
+	// textfields layer
+	Strict bool
+	// MaxBytes is the largest input that will be parsed
+	MaxBytes int
+	// MaxDepth is the deepest nesting of elements that will be parsed
+	MaxDepth int
+}
+
+const (
+	// DefaultMaxBytes leaves room for photos embedded as data: URIs,
+	// which base64 makes a third bigger than the image
+	DefaultMaxBytes = 64 << 20
+	DefaultMaxDepth = 128
+)
+
+// DefaultParseOptions are generous for anything drawn in inkscape, but stop
+// a hostile file from using up memory or stack. It is a func, so that one
+// caller can't change the limits for every other.
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{
+		MaxBytes: DefaultMaxBytes,
+		MaxDepth: DefaultMaxDepth,
+	}
+}
+
+var (
+	ErrEmptySvg     = errors.New("svg is empty")
+	ErrSvgTooLarge  = errors.New("svg is too large")
+	ErrSvgTooDeep   = errors.New("svg is nested too deeply")
+	ErrUnknownShape = errors.New("element is not used on this layer")
+)
+
+const (
+	svgNamespace      = "http://www.w3.org/2000/svg"
+	inkscapeNamespace = "http://www.inkscape.org/namespaces/inkscape"
+)
+
+// layerElements are the elements that are read from each layer, which
+// are the only ones allowed in strict mode (as well as groups, which are
+// always allowed)
+var layerElements = map[string][]string{
+	"anchors":         []string{"path"},
+	"textfields":      []string{"rect"},
+	"textprefills":    []string{"rect"},
+	"placeholders":    []string{"rect"},
+	"pages":           []string{"rect"},
+	"images":          []string{"rect", "image"},
+	CheckBoxesLayer:   []string{"rect"},
+	DropDownsLayer:    []string{"rect"},
+	ComboBoxesLayer:   []string{"rect"},
+	RadioButtonsLayer: []string{"rect", "circle", "path"},
+}
+
+// ParseSvg is kept for existing callers, but ignores errors, so a corrupt
+// file looks like an empty one. Use ParseSvgWithOptions instead.
+func ParseSvg(input []byte) *Csvg__svg {
+
+	var svg Csvg__svg
+
+	xml.Unmarshal(input, &svg)
+
+	return &svg
+}
+
+// ParseSvgWithOptions unmarshals an svg, returning an error if it is
+// malformed (e.g. truncated), too large, too deeply nested, or in strict
+// mode, has elements in a known layer that the parser won't use.
+func ParseSvgWithOptions(input []byte, options ParseOptions) (*Csvg__svg, error) {
+
+	if len(bytes.TrimSpace(input)) == 0 {
+		return nil, ErrEmptySvg
+	}
+
+	if options.MaxBytes > 0 && len(input) > options.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes, but the limit is %d", ErrSvgTooLarge, len(input), options.MaxBytes)
+	}
+
+	err := checkSvgStructure(input, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var svg Csvg__svg
+
+	err = xml.Unmarshal(input, &svg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svg, nil
+}
+
+// readSvg reads no more than one byte past the default size limit, so that
+// ParseSvgWithOptions can still tell the input was too large
+func readSvg(r io.Reader) ([]byte, error) {
+
+	r = io.LimitReader(r, DefaultMaxBytes+1)
+
+	return ioutil.ReadAll(r)
+}
+
+// svgFrame is an open element, noting which layer it is in
+type svgFrame struct {
+	name  xml.Name
+	layer string
+}
+
+// checkSvgStructure reads through the tokens without building anything,
+// so that depth is checked before unmarshalling recurses into the tree
+func checkSvgStructure(input []byte, options ParseOptions) error {
+
+	decoder := xml.NewDecoder(bytes.NewReader(input))
+
+	var stack []svgFrame
+
+	for {
+
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("malformed svg: %w", err)
+		}
+
+		switch t := token.(type) {
+
+		case xml.StartElement:
+
+			if options.MaxDepth > 0 && len(stack) >= options.MaxDepth {
+				return fmt.Errorf("%w: more than %d elements deep at <%s>", ErrSvgTooDeep, options.MaxDepth, t.Name.Local)
+			}
+
+			frame := svgFrame{name: t.Name}
+
+			if len(stack) > 0 {
+
+				parent := stack[len(stack)-1]
+				frame.layer = parent.layer
+
+				if t.Name.Local == "g" && t.Name.Space == svgNamespace {
+					// as per getLayerGroups
+					if len(stack) == 1 || attr(t, inkscapeNamespace, "groupmode") == "layer" {
+						frame.layer = attr(t, inkscapeNamespace, "label")
+					}
+				} else if options.Strict && parent.name.Local == "g" && !allowedInLayer(parent.layer, t.Name) {
+					return &ElementError{
+						Layer: parent.layer,
+						ID:    attr(t, "", "id"),
+						Err:   fmt.Errorf("%w: <%s>", ErrUnknownShape, t.Name.Local),
+					}
+				}
+			}
+
+			stack = append(stack, frame)
+
+		case xml.EndElement:
+
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("malformed svg: %w", io.ErrUnexpectedEOF)
+	}
+
+	return nil
+}
+
+func allowedInLayer(layer string, name xml.Name) bool {
+
+	allowed, known := layerElements[layer]
+	if !known {
+		return true
+	}
+
+	if name.Space != svgNamespace {
+		return false
+	}
+
+	switch name.Local {
+	case "g", "title", "desc":
+		return true
+	}
+
+	for _, local := range allowed {
+		if name.Local == local {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attr(t xml.StartElement, space, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package parsesvg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSvgWithOptions(t *testing.T) {
+
+	svg, err := ParseSvgWithOptions([]byte(radioButtonsSVG), ParseOptions{Strict: true})
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if svg == nil || len(svg.Cg__svg) != 1 {
+		t.Errorf("Expected one layer, got %v", svg)
+	}
+
+	_, err = ParseSvgWithOptions([]byte(""), DefaultParseOptions())
+	if !errors.Is(err, ErrEmptySvg) {
+		t.Errorf("Expected ErrEmptySvg, got %v", err)
+	}
+
+	truncated := radioButtonsSVG[:len(radioButtonsSVG)/2]
+	_, err = ParseSvgWithOptions([]byte(truncated), DefaultParseOptions())
+	if err == nil {
+		t.Errorf("Expected an error for a truncated svg")
+	}
+
+	_, err = ParseSvgWithOptions([]byte(radioButtonsSVG), ParseOptions{MaxBytes: 100})
+	if !errors.Is(err, ErrSvgTooLarge) {
+		t.Errorf("Expected ErrSvgTooLarge, got %v", err)
+	}
+
+	deep := `<svg xmlns="http://www.w3.org/2000/svg">` + strings.Repeat("<g>", 20) + strings.Repeat("</g>", 20) + `</svg>`
+	_, err = ParseSvgWithOptions([]byte(deep), ParseOptions{MaxDepth: 10})
+	if !errors.Is(err, ErrSvgTooDeep) {
+		t.Errorf("Expected ErrSvgTooDeep, got %v", err)
+	}
+	_, err = ParseSvgWithOptions([]byte(deep), ParseOptions{MaxDepth: 21})
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	ellipse := strings.Replace(radioButtonsSVG, `<circle`, `<ellipse id="ellipse1" /><circle`, 1)
+
+	_, err = ParseSvgWithOptions([]byte(ellipse), DefaultParseOptions())
+	if err != nil {
+		t.Errorf("Unknown elements should be ignored unless strict, got %v", err)
+	}
+
+	_, err = ParseSvgWithOptions([]byte(ellipse), ParseOptions{Strict: true})
+	if !errors.Is(err, ErrUnknownShape) {
+		t.Fatalf("Expected ErrUnknownShape, got %v", err)
+	}
+
+	var ee *ElementError
+	if !errors.As(err, &ee) || ee.Layer != RadioButtonsLayer || ee.ID != "ellipse1" {
+		t.Errorf("Expected an ElementError for ellipse1 on the radiobuttons layer, got %v", err)
+	}
+}
+
+func TestDefineLadderWithoutMetadata(t *testing.T) {
+
+	bare := strings.Replace(radioButtonsSVG, `<metadata
+     id="metadata1" />`, "", 1)
+
+	ladder, err := DefineLadderFromSVG([]byte(bare))
+	if err != nil || len(ladder.RadioGroups) != 2 {
+		t.Errorf("Expected a ladder without metadata, got %v %v", ladder, err)
+	}
+}
@@ -0,0 +1,91 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ElementError says which element of which file could not be parsed, and
+// why, so that the designer can find the offending object in inkscape.
+// Fields are left empty when they are not known, or don't apply.
+type ElementError struct {
+	File      string
+	Layer     string
+	ID        string
+	Title     string
+	Attribute string
+	Value     string
+	Err       error
+}
+
+func (e *ElementError) Error() string {
+
+	var where []string
+
+	if e.File != "" {
+		where = append(where, e.File)
+	}
+	if e.Layer != "" {
+		where = append(where, fmt.Sprintf("layer %s", e.Layer))
+	}
+	if e.ID != "" {
+		where = append(where, fmt.Sprintf("element %s", e.ID))
+	}
+	if e.Title != "" {
+		where = append(where, fmt.Sprintf("titled %q", e.Title))
+	}
+	if e.Attribute != "" {
+		where = append(where, fmt.Sprintf("%s=%q", e.Attribute, e.Value))
+	}
+
+	return fmt.Sprintf("%s: %v", strings.Join(where, ", "), e.Err)
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// attributeError is for when the value of an attribute can't be used
+func attributeError(id, attribute, value string, err error) error {
+	return &ElementError{ID: id, Attribute: attribute, Value: value, Err: err}
+}
+
+// elementError adds the layer, id and title of the element being parsed
+// to an error, keeping anything more specific that is already known
+func elementError(err error, layer, id string, title *Ctitle__svg) error {
+
+	var ee *ElementError
+
+	if !errors.As(err, &ee) {
+		ee = &ElementError{Err: err}
+		err = ee
+	}
+
+	if ee.Layer == "" {
+		ee.Layer = layer
+	}
+	if ee.ID == "" {
+		ee.ID = id
+	}
+	if ee.Title == "" && title != nil { //avoid seg fault, obvs
+		ee.Title = title.String
+	}
+
+	return err
+}
+
+// fileError adds the file name to an error from parsing that file
+func fileError(err error, file string) error {
+
+	var ee *ElementError
+
+	if errors.As(err, &ee) {
+		if ee.File == "" {
+			ee.File = file
+		}
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", file, err)
+}
@@ -0,0 +1,50 @@
+package parsesvg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestElementError(t *testing.T) {
+
+	bad := strings.Replace(radioButtonsSVG, `width="10"`, `width="10xx"`, 1)
+
+	_, err := DefineLadderFromSVG([]byte(bad))
+	if err == nil {
+		t.Fatalf("Expected an error for a width in unknown units")
+	}
+
+	err = fileError(err, "test/radio.svg")
+
+	var ee *ElementError
+	if !errors.As(err, &ee) {
+		t.Fatalf("Expected an *ElementError, got %T %v", err, err)
+	}
+
+	want := ElementError{
+		File:      "test/radio.svg",
+		Layer:     "radiobuttons",
+		ID:        "rect-tab-2",
+		Title:     "q1-radio-3",
+		Attribute: "width",
+		Value:     "10xx",
+	}
+
+	got := *ee
+	got.Err = nil
+
+	if got != want {
+		t.Errorf("Element error wrong\n%v\n%v", want, got)
+	}
+
+	msg := `test/radio.svg, layer radiobuttons, element rect-tab-2, titled "q1-radio-3", width="10xx": `
+	if !strings.HasPrefix(err.Error(), msg) {
+		t.Errorf("Error message wrong\n%s\n%s", msg, err.Error())
+	}
+
+	other := errors.New("not an element")
+	if !errors.Is(fileError(other, "test/radio.svg"), other) {
+		t.Errorf("fileError should wrap errors that aren't from an element")
+	}
+}
@@ -0,0 +1,625 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+	"github.com/timdrysdale/unipdf/v3/annotator"
+	"github.com/timdrysdale/unipdf/v3/core"
+	"github.com/timdrysdale/unipdf/v3/model"
+)
+
+// pageWidgets collects the widgets of every field on a page, of whatever
+// kind, so that they can be added to the page in tab order, because
+// viewers tab through widgets in the order of the page's annotations
+type pageWidgets []pageWidget
+
+type pageWidget struct {
+	tab     int64
+	widgets []*model.PdfAnnotation
+}
+
+func (w *pageWidgets) add(tab int64, widgets ...*model.PdfAnnotation) {
+	*w = append(*w, pageWidget{tab: tab, widgets: widgets})
+}
+
+// addTo adds the widgets to the page in tab order, keeping the order they
+// were collected in for those with the same tab sequence
+func (w pageWidgets) addTo(page *model.PdfPage) {
+
+	sort.SliceStable(w, func(i, j int) bool {
+		return w[i].tab < w[j].tab
+	})
+
+	for _, pw := range w {
+		for _, widget := range pw.widgets {
+			page.AddAnnotation(widget)
+		}
+	}
+}
+
+// addChoiceField puts a dropdown, or if editable, a combobox, onto the page
+// and into the form. Viewers are asked to draw the appearance, so that the
+// default choice shows up without us having to typeset it ourselves
+func addChoiceField(page *model.PdfPage, form *model.PdfAcroForm, widgets *pageWidgets, pageNumber int, dd DropDown, dim geo.Dim, editable bool) error {
+
+	name := fmt.Sprintf("page-%03d-%s", pageNumber, dd.ID)
+
+	chopt := annotator.ComboboxFieldOptions{Choices: dd.Options.Choices}
+
+	choice, err := annotator.NewComboboxField(page, name, pdfRect(dd.Rect, dim), chopt)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error making choice field %s: %v\n", name, err))
+	}
+
+	flags := model.FieldFlagCombo
+
+	if editable {
+		flags = flags | model.FieldFlagEdit
+	}
+
+	addFieldFlags(choice.PdfField, flags)
+
+	if dd.Options.Default != "" {
+		choice.V = core.MakeString(dd.Options.Default)
+		choice.DV = core.MakeString(dd.Options.Default)
+		form.NeedAppearances = core.MakeBool(true)
+	}
+
+	*form.Fields = append(*form.Fields, choice.PdfField)
+	widgets.add(dd.TabSequence, choice.Annotations[0].PdfAnnotation)
+
+	return nil
+}
+
+// fieldFonts maps the standard fonts onto the resource names that viewers
+// conventionally use for them in a form's default resources
+var fieldFonts = map[string]string{
+	"Courier":               "Cour",
+	"Courier-Bold":          "CoBo",
+	"Courier-Oblique":       "CoOb",
+	"Courier-BoldOblique":   "CoBO",
+	"Helvetica":             "Helv",
+	"Helvetica-Bold":        "HeBo",
+	"Helvetica-Oblique":     "HeOb",
+	"Helvetica-BoldOblique": "HeBO",
+	"Symbol":                "Symb",
+	"Times-Roman":           "TiRo",
+	"Times-Bold":            "TiBo",
+	"Times-Italic":          "TiIt",
+	"Times-BoldItalic":      "TiBI",
+	"ZapfDingbats":          "ZaDb",
+}
+
+// fieldAlignments are the quadding (Q) values for text fields
+var fieldAlignments = map[string]int64{
+	"left":   0,
+	"center": 1,
+	"right":  2,
+}
+
+// applyTextFieldOptions sets the flags, tooltip, alignment and default
+// appearance on a text field. MaxLen and the prefill are handled by the
+// annotator when the field is made.
+func applyTextFieldOptions(form *model.PdfAcroForm, textf *model.PdfFieldText, opt TextFieldOptions) error {
+
+	flags := model.FieldFlagClear
+
+	if opt.Multiline {
+		flags = flags | model.FieldFlagMultiline
+	}
+	if opt.Comb {
+		flags = flags | model.FieldFlagComb
+	}
+	if opt.ReadOnly {
+		flags = flags | model.FieldFlagReadOnly
+	}
+	if opt.Required {
+		flags = flags | model.FieldFlagRequired
+	}
+
+	if flags != model.FieldFlagClear {
+		addFieldFlags(textf.PdfField, flags)
+	}
+
+	if opt.Tooltip != "" {
+		textf.TU = core.MakeString(opt.Tooltip)
+	}
+
+	if opt.Align != "" {
+		q, ok := fieldAlignments[opt.Align]
+		if !ok {
+			return errors.New(fmt.Sprintf("unknown alignment %s", opt.Align))
+		}
+		textf.Q = core.MakeInteger(q)
+	}
+
+	if opt.Font != "" || opt.FontSize > 0 {
+
+		font := opt.Font
+		if font == "" {
+			font = string(model.HelveticaName)
+		}
+
+		if !knownFont(font) {
+			return errors.New(fmt.Sprintf("unknown font %s", font))
+		}
+
+		name := fieldFontName(font)
+
+		if form.DR == nil {
+			form.DR = model.NewPdfPageResources()
+		}
+
+		if !form.DR.HasFontByName(core.PdfObjectName(name)) {
+			pdfFont, err := newFieldFont(font)
+			if err != nil {
+				return err
+			}
+			err = form.DR.SetFontByName(core.PdfObjectName(name), pdfFont.ToPdfObject())
+			if err != nil {
+				return err
+			}
+		}
+
+		// a size of zero asks the viewer to fit the text to the field
+		textf.DA = core.MakeString(fmt.Sprintf("/%s %g Tf 0 g", name, opt.FontSize))
+	}
+
+	// viewers must draw the field themselves for these options to show
+	if opt.Comb || opt.Align != "" || textf.DA != nil {
+		form.NeedAppearances = core.MakeBool(true)
+	}
+
+	if opt.Numeric {
+		actions := numericActions(opt)
+		if len(opt.Sum) > 0 {
+			actions.Set("C", javaScriptAction(sumCalculation(opt.Sum)))
+		}
+		textf.AA = actions
+	}
+
+	return nil
+}
+
+// numericActions returns the additional-actions dictionary that stops
+// anything but a number being typed (K), tidies the number for display (F)
+// and checks it is in range (V). The display is only tidied when there is
+// a step, because otherwise we don't know how many decimal places are wanted.
+func numericActions(opt TextFieldOptions) *core.PdfObjectDictionary {
+
+	decimals := stepDecimals(opt.Step)
+
+	actions := core.MakeDict()
+
+	actions.Set("K", javaScriptAction(fmt.Sprintf("AFNumber_Keystroke(%d, 1, 0, 0, \"\", true);", decimals)))
+
+	if opt.Step > 0 {
+		actions.Set("F", javaScriptAction(fmt.Sprintf("AFNumber_Format(%d, 1, 0, 0, \"\", true);", decimals)))
+	}
+
+	actions.Set("V", javaScriptAction(numericValidation(opt)))
+
+	return actions
+}
+
+// numericValidation is the javascript that rejects a number that is out of
+// range, or not a whole number of steps (counted from min, if there is one)
+func numericValidation(opt TextFieldOptions) string {
+
+	var checks []string
+	var limits []string
+
+	if opt.Min != nil {
+		checks = append(checks, fmt.Sprintf("v < %g", *opt.Min))
+		limits = append(limits, fmt.Sprintf("at least %g", *opt.Min))
+	}
+
+	if opt.Max != nil {
+		checks = append(checks, fmt.Sprintf("v > %g", *opt.Max))
+		limits = append(limits, fmt.Sprintf("at most %g", *opt.Max))
+	}
+
+	if opt.Step > 0 {
+		origin := 0.0
+		if opt.Min != nil {
+			origin = *opt.Min
+		}
+		checks = append(checks, fmt.Sprintf("Math.abs((v - %g) / %g - Math.round((v - %g) / %g)) > 1e-9", origin, opt.Step, origin, opt.Step))
+		limits = append(limits, fmt.Sprintf("in steps of %g", opt.Step))
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, "isNaN(v)")
+		limits = append(limits, "a number")
+	} else {
+		checks = append([]string{"isNaN(v)"}, checks...)
+	}
+
+	return fmt.Sprintf("if (event.value !== \"\") { var v = Number(event.value); if (%s) { app.alert(\"Please enter a number %s\"); event.rc = false; } }",
+		strings.Join(checks, " || "), strings.Join(limits, ", "))
+}
+
+// sumCalculation is the javascript that sets a field to the total of the
+// named fields, using the viewer's built-in, so that blanks count as zero
+func sumCalculation(names []string) string {
+
+	quoted := []string{}
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", name))
+	}
+
+	return fmt.Sprintf("AFSimple_Calculate(\"SUM\", new Array(%s));", strings.Join(quoted, ", "))
+}
+
+// expandSum turns the list of fields to be summed into textfield IDs. An
+// entry ending in * stands for every textfield whose ID starts with the
+// rest of it, e.g. qn-part-mark-* for all the mark boxes, and may match
+// none, e.g. when there are no parts, so the total is zero. A field that
+// is named in full must exist, in case the name is mistyped.
+func expandSum(id string, sum []string, ids []string) ([]string, error) {
+
+	var expanded []string
+
+	seen := make(map[string]bool)
+
+	for _, pattern := range sum {
+
+		matched := false
+
+		for _, candidate := range ids {
+
+			if candidate == id {
+				continue
+			}
+
+			if strings.HasSuffix(pattern, "*") {
+				if !strings.HasPrefix(candidate, strings.TrimSuffix(pattern, "*")) {
+					continue
+				}
+			} else if candidate != pattern {
+				continue
+			}
+
+			matched = true
+
+			if !seen[candidate] {
+				seen[candidate] = true
+				expanded = append(expanded, candidate)
+			}
+		}
+
+		if !matched && !strings.HasSuffix(pattern, "*") {
+			return nil, errors.New(fmt.Sprintf("textfield %s sums %s, but there is no such textfield", id, pattern))
+		}
+	}
+
+	return expanded, nil
+}
+
+// calculationOrder puts the calculated fields in an order where any field
+// that is summed by another is calculated first, e.g. subtotals before the
+// total. Otherwise the order is kept as given.
+func calculationOrder(names []string, sums map[string][]string) ([]string, error) {
+
+	var order []string
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := make(map[string]int)
+
+	var visit func(name string) error
+
+	visit = func(name string) error {
+
+		switch state[name] {
+		case visiting:
+			return errors.New(fmt.Sprintf("calculated field %s depends on itself", name))
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+
+		for _, dependency := range sums[name] {
+			if _, ok := sums[dependency]; ok {
+				err := visit(dependency)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		state[name] = done
+		order = append(order, name)
+
+		return nil
+	}
+
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// setCalculationOrder tells the viewer which order to recalculate the
+// fields in (CO), since it does not work this out for itself
+func setCalculationOrder(form *model.PdfAcroForm, names []string, fields []*model.PdfField, sums map[string][]string) error {
+
+	order, err := calculationOrder(names, sums)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]*model.PdfField)
+	for i, name := range names {
+		byName[name] = fields[i]
+	}
+
+	co := core.MakeArray()
+	for _, name := range order {
+		co.Append(byName[name].GetContainingPdfObject())
+	}
+
+	form.CO = co
+
+	return nil
+}
+
+// stepDecimals is the number of decimal places needed to show a step,
+// e.g. 0 for 1, 1 for 0.5 and 2 for 0.25
+func stepDecimals(step float64) int {
+
+	if step <= 0 {
+		return 0
+	}
+
+	for decimals := 0; decimals < 6; decimals++ {
+		scaled := step * math.Pow(10, float64(decimals))
+		if math.Abs(scaled-math.Round(scaled)) < 1e-9 {
+			return decimals
+		}
+	}
+
+	return 6
+}
+
+// javaScriptAction wraps a script as an action, for use in a field's
+// additional-actions dictionary
+func javaScriptAction(js string) *core.PdfObjectDictionary {
+
+	action := core.MakeDict()
+	action.Set("Type", core.MakeName("Action"))
+	action.Set("S", core.MakeName("JavaScript"))
+	action.Set("JS", core.MakeString(js))
+
+	return action
+}
+
+// addFieldFlags sets flags on a field, keeping any that are already set
+func addFieldFlags(field *model.PdfField, flags model.FieldFlag) {
+
+	existing := int64(0)
+
+	if field.Ff != nil {
+		existing = int64(*field.Ff)
+	}
+
+	field.Ff = core.MakeInteger(existing | int64(flags))
+}
+
+// setOnState names a checkbox's on state after the value it exports, and
+// sets whether it is ticked. Viewers export the name of the on appearance,
+// which the annotator always calls Yes, and many ignore Opt on checkboxes.
+func setOnState(checkbox *model.PdfFieldButton, onValue string, checked bool) {
+
+	state := core.MakeName("Off")
+	if checked {
+		state = core.MakeName(onValue)
+	}
+
+	checkbox.V = state
+
+	for _, widget := range checkbox.Annotations {
+
+		widget.AS = state
+
+		appearance, ok := core.GetDict(widget.AP)
+		if !ok {
+			continue
+		}
+
+		// normal and down appearances, whichever the annotator drew
+		for _, key := range []core.PdfObjectName{"N", "D"} {
+
+			states, ok := core.GetDict(appearance.Get(key))
+			if !ok {
+				continue
+			}
+
+			for _, name := range states.Keys() {
+				if name != "Off" && string(name) != onValue {
+					states.Set(core.PdfObjectName(onValue), states.Get(name))
+					states.Remove(name)
+				}
+			}
+		}
+	}
+}
+
+// addRadioGroup puts a group of radio buttons onto the page, as a single
+// parent field with one widget per button, so that only one can be on
+func addRadioGroup(page *model.PdfPage, form *model.PdfAcroForm, widgets *pageWidgets, pageNumber int, rg RadioGroup, dim geo.Dim) error {
+
+	name := fmt.Sprintf("page-%03d-%s", pageNumber, rg.ID)
+
+	field := model.NewPdfField()
+	field.FT = core.MakeName("Btn")
+	field.T = core.MakeString(name)
+
+	selected := "Off"
+	if rg.Default != "" {
+		selected = rg.Default
+	}
+	field.V = core.MakeName(selected)
+
+	addFieldFlags(field, model.FieldFlagRadio|model.FieldFlagNoToggleToOff)
+
+	var buttons []*model.PdfAnnotation
+
+	for _, rb := range rg.Buttons {
+
+		rect := pdfRect(rb.Rect, dim)
+
+		// widget rects are [llx lly urx ury]
+		llx := math.Min(rect[0], rect[2])
+		lly := math.Min(rect[1], rect[3])
+		urx := math.Max(rect[0], rect[2])
+		ury := math.Max(rect[1], rect[3])
+
+		on, err := radioAppearance(urx-llx, ury-lly, true)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error making radio button %s: %v\n", rb.ID, err))
+		}
+		off, err := radioAppearance(urx-llx, ury-lly, false)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error making radio button %s: %v\n", rb.ID, err))
+		}
+
+		normal := core.MakeDict()
+		normal.Set(core.PdfObjectName(rb.Value), on.ToPdfObject())
+		normal.Set("Off", off.ToPdfObject())
+
+		appearance := core.MakeDict()
+		appearance.Set("N", normal)
+
+		characteristics := core.MakeDict()
+		characteristics.Set("BC", core.MakeArrayFromFloats([]float64{0, 0, 0}))
+
+		state := "Off"
+		if rb.Value == rg.Default {
+			state = rb.Value
+		}
+
+		widget := model.NewPdfAnnotationWidget()
+		widget.Rect = core.MakeArrayFromFloats([]float64{llx, lly, urx, ury})
+		widget.P = page.ToPdfObject()
+		widget.F = core.MakeInteger(4) // print
+		widget.Parent = field.GetContainingPdfObject()
+		widget.AP = appearance
+		widget.AS = core.MakeName(state)
+		widget.MK = characteristics
+
+		field.Annotations = append(field.Annotations, widget)
+		buttons = append(buttons, widget.PdfAnnotation)
+	}
+
+	// the buttons of a group are tabbed to together
+	widgets.add(rg.TabSequence, buttons...)
+
+	*form.Fields = append(*form.Fields, field)
+
+	return nil
+}
+
+// radioAppearance draws a circular button that fits in a box of size w,h,
+// with a dot in the middle if it is on
+func radioAppearance(w, h float64, on bool) (*model.XObjectForm, error) {
+
+	r := math.Min(w, h) / 2
+
+	content := "q\n0 G\n1 w\n" + circlePath(w/2, h/2, r-0.5) + "S\n"
+
+	if on {
+		content = content + "0 g\n" + circlePath(w/2, h/2, r/2) + "f\n"
+	}
+
+	content = content + "Q\n"
+
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, w, h})
+
+	err := xform.SetContentStream([]byte(content), core.NewRawEncoder())
+	if err != nil {
+		return nil, err
+	}
+
+	return xform, nil
+}
+
+// circlePath approximates a circle with four bezier curves
+func circlePath(cx, cy, r float64) string {
+
+	k := 0.5523 * r // control point offset for a quarter circle
+
+	return fmt.Sprintf("%.3f %.3f m\n", cx+r, cy) +
+		fmt.Sprintf("%.3f %.3f %.3f %.3f %.3f %.3f c\n", cx+r, cy+k, cx+k, cy+r, cx, cy+r) +
+		fmt.Sprintf("%.3f %.3f %.3f %.3f %.3f %.3f c\n", cx-k, cy+r, cx-r, cy+k, cx-r, cy) +
+		fmt.Sprintf("%.3f %.3f %.3f %.3f %.3f %.3f c\n", cx-r, cy-k, cx-k, cy-r, cx, cy-r) +
+		fmt.Sprintf("%.3f %.3f %.3f %.3f %.3f %.3f c\n", cx+k, cy-r, cx+r, cy-k, cx+r, cy)
+}
+
+// fieldCounts are how many fields of each kind a spread has, so that the
+// fields added by a ladder can be found
+type fieldCounts struct {
+	textFields, checkBoxes, dropDowns, comboBoxes, radioGroups int
+}
+
+func (s *Spread) fieldCounts() fieldCounts {
+	return fieldCounts{
+		textFields:  len(s.TextFields),
+		checkBoxes:  len(s.CheckBoxes),
+		dropDowns:   len(s.DropDowns),
+		comboBoxes:  len(s.ComboBoxes),
+		radioGroups: len(s.RadioGroups),
+	}
+}
+
+// followTabs moves the tab sequences of the fields added since from to
+// after tab, the last tab sequence used on the page, and returns the new
+// last tab sequence. Each ladder numbers its own fields from one, so this
+// makes the page tab through each ladder in turn.
+func (s *Spread) followTabs(from fieldCounts, tab int64) int64 {
+
+	last := tab
+
+	follow := func(seq *int64) {
+		*seq = *seq + tab + 1
+		if *seq > last {
+			last = *seq
+		}
+	}
+
+	for i := from.textFields; i < len(s.TextFields); i++ {
+		follow(&s.TextFields[i].TabSequence)
+	}
+	for i := from.checkBoxes; i < len(s.CheckBoxes); i++ {
+		follow(&s.CheckBoxes[i].TabSequence)
+	}
+	for i := from.dropDowns; i < len(s.DropDowns); i++ {
+		follow(&s.DropDowns[i].TabSequence)
+	}
+	for i := from.comboBoxes; i < len(s.ComboBoxes); i++ {
+		follow(&s.ComboBoxes[i].TabSequence)
+	}
+	for i := from.radioGroups; i < len(s.RadioGroups); i++ {
+		rg := &s.RadioGroups[i]
+		follow(&rg.TabSequence)
+		for j := range rg.Buttons {
+			rg.Buttons[j].TabSequence = rg.Buttons[j].TabSequence + tab + 1
+		}
+	}
+
+	return last
+}
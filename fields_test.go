@@ -0,0 +1,211 @@
+package parsesvg
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/timdrysdale/unipdf/v3/annotator"
+	"github.com/timdrysdale/unipdf/v3/core"
+	"github.com/timdrysdale/unipdf/v3/model"
+)
+
+func TestStepDecimals(t *testing.T) {
+
+	tests := map[float64]int{0: 0, 1: 0, 2: 0, 0.5: 1, 0.25: 2, 0.1: 1}
+
+	for step, want := range tests {
+		if got := stepDecimals(step); got != want {
+			t.Errorf("step %g: got %d, want %d", step, got, want)
+		}
+	}
+}
+
+func TestNumericValidation(t *testing.T) {
+
+	min := 0.0
+	max := 10.0
+
+	js := numericValidation(TextFieldOptions{Numeric: true, Min: &min, Max: &max, Step: 0.5})
+
+	for _, want := range []string{"isNaN(v)", "v < 0", "v > 10", "(v - 0) / 0.5", "event.rc = false"} {
+		if !strings.Contains(js, want) {
+			t.Errorf("validation script missing %q\n%s", want, js)
+		}
+	}
+
+	js = numericValidation(TextFieldOptions{Numeric: true})
+
+	if strings.Contains(js, "v <") || strings.Contains(js, "v >") || !strings.Contains(js, "isNaN(v)") {
+		t.Errorf("validation script without limits should only check for a number\n%s", js)
+	}
+}
+
+func TestExpandSum(t *testing.T) {
+
+	ids := []string{"qn-part-mark-0", "qn-part-mark-1", "qn-part-moderate-0", "subtotal", "qn-total-mark"}
+
+	got, err := expandSum("qn-total-mark", []string{"qn-part-mark-*", "subtotal", "qn-part-mark-1"}, ids)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	want := []string{"qn-part-mark-0", "qn-part-mark-1", "subtotal"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// no parts, so nothing to add up
+	got, err = expandSum("qn-total-mark", []string{"qn-part-check-*"}, ids)
+	if err != nil || len(got) != 0 {
+		t.Errorf("Expected an empty sum for a wildcard that matches no fields, got %v %v", got, err)
+	}
+
+	_, err = expandSum("qn-total-mark", []string{"subtotl"}, ids)
+	if err == nil {
+		t.Errorf("Expected an error for a sum of a field that doesn't exist")
+	}
+}
+
+func TestCalculationOrder(t *testing.T) {
+
+	sums := map[string][]string{
+		"total":    []string{"subtotal", "c"},
+		"subtotal": []string{"a", "b"},
+	}
+
+	got, err := calculationOrder([]string{"total", "subtotal"}, sums)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	want := []string{"subtotal", "total"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	sums["subtotal"] = []string{"a", "total"}
+
+	_, err = calculationOrder([]string{"total", "subtotal"}, sums)
+	if err == nil {
+		t.Errorf("Expected an error for circular sums")
+	}
+}
+
+func TestTabOrderAcrossKinds(t *testing.T) {
+
+	spread := &Spread{}
+
+	// a ladder with a textfield, radio group and textfield, tabbed 1, 2, 3
+	from := spread.fieldCounts()
+	spread.TextFields = append(spread.TextFields, TextField{ID: "first", TabSequence: 1}, TextField{ID: "third", TabSequence: 3})
+	spread.RadioGroups = append(spread.RadioGroups, RadioGroup{ID: "choice", TabSequence: 2, Buttons: []RadioButton{RadioButton{TabSequence: 2}}})
+	spread.CheckBoxes = append(spread.CheckBoxes, CheckBox{ID: "seen", TabSequence: 4})
+	tab := spread.followTabs(from, 0)
+
+	// a mark box made from a placeholder, which has no tab sequence
+	from = spread.fieldCounts()
+	spread.TextFields = append(spread.TextFields, TextField{ID: "mark"})
+	tab = spread.followTabs(from, tab)
+
+	// another ladder, numbered from one again
+	from = spread.fieldCounts()
+	spread.DropDowns = append(spread.DropDowns, DropDown{ID: "grade", TabSequence: 1})
+	tab = spread.followTabs(from, tab)
+
+	if tab != 8 {
+		t.Errorf("Expected the last tab to be 8, got %d", tab)
+	}
+
+	page := model.NewPdfPage()
+	widgets := pageWidgets{}
+	made := map[*model.PdfAnnotation]string{}
+
+	// added kind by kind, as they are when rendering
+	add := func(name string, tab int64) {
+		widget := model.NewPdfAnnotationWidget().PdfAnnotation
+		made[widget] = name
+		widgets.add(tab, widget)
+	}
+
+	for _, tf := range spread.TextFields {
+		add(tf.ID, tf.TabSequence)
+	}
+	for _, cb := range spread.CheckBoxes {
+		add(cb.ID, cb.TabSequence)
+	}
+	for _, dd := range spread.DropDowns {
+		add(dd.ID, dd.TabSequence)
+	}
+	for _, rg := range spread.RadioGroups {
+		add(rg.ID, rg.TabSequence)
+	}
+
+	widgets.addTo(page)
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, a := range annotations {
+		got = append(got, made[a])
+	}
+
+	want := []string{"first", "choice", "third", "seen", "mark", "grade"}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Tab order wrong\n%v\n%v", want, got)
+	}
+}
+
+func TestSetOnState(t *testing.T) {
+
+	for _, checked := range []bool{true, false} {
+
+		checkbox, err := annotator.NewCheckboxField(model.NewPdfPage(), "page-001-seen", []float64{0, 0, 10, 10}, annotator.CheckboxFieldOptions{Checked: checked})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		setOnState(checkbox, "Seen", checked)
+
+		// what a viewer exports when the box is ticked, or not
+		want := "Off"
+		if checked {
+			want = "Seen"
+		}
+
+		if v, ok := core.GetName(checkbox.V); !ok || string(*v) != want {
+			t.Errorf("Checked %v: exports %v, expected %s", checked, checkbox.V, want)
+		}
+
+		widget := checkbox.Annotations[0]
+
+		if as, ok := core.GetName(widget.AS); !ok || string(*as) != want {
+			t.Errorf("Checked %v: appearance state %v, expected %s", checked, widget.AS, want)
+		}
+
+		appearance, ok := core.GetDict(widget.AP)
+		if !ok {
+			t.Fatal("Expected the checkbox to have appearances")
+		}
+
+		normal, ok := core.GetDict(appearance.Get("N"))
+		if !ok {
+			t.Fatal("Expected the checkbox to have normal appearances")
+		}
+
+		var states []string
+		for _, name := range normal.Keys() {
+			states = append(states, string(name))
+		}
+		sort.Strings(states)
+
+		if !reflect.DeepEqual(states, []string{"Off", "Seen"}) {
+			t.Errorf("Checked %v: appearance states %v, expected Off and Seen", checked, states)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package parsesvg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/timdrysdale/unipdf/v3/creator"
+	"github.com/timdrysdale/unipdf/v3/model"
+)
+
+// fontFile is a registered font, kept as the file so each pdf can embed
+// its own copy, with the name it has in the form's resources
+type fontFile struct {
+	data     []byte
+	resource string
+}
+
+// registeredFonts are the TrueType fonts that can be used by name, as
+// well as the 14 standard fonts
+var registeredFonts = struct {
+	sync.RWMutex
+	fonts map[string]fontFile
+}{fonts: make(map[string]fontFile)}
+
+// RegisterFont makes a TrueType font (.ttf, or .otf with TrueType
+// outlines) usable by name, as the textFont of a prefill or the font of a
+// textfield. Register fonts before parsing designs that use them, because
+// an unknown font is an error. Prefills embed only the characters they
+// use, but textfields embed the whole font, because the marker could type
+// anything.
+func RegisterFont(name string, data []byte) error {
+
+	if name == "" {
+		return errors.New("can't register a font without a name")
+	}
+
+	if _, ok := fieldFonts[name]; ok {
+		return errors.New(fmt.Sprintf("can't register font %s, because it is one of the standard fonts", name))
+	}
+
+	// check it can be embedded now, rather than when rendering
+	_, err := model.NewCompositePdfFontFromTTF(bytes.NewReader(data))
+	if err != nil {
+		return errors.New(fmt.Sprintf("can't register font %s: %v", name, err))
+	}
+
+	registeredFonts.Lock()
+	defer registeredFonts.Unlock()
+
+	// numbered in the order they were registered, because names that
+	// differ only in spaces or punctuation would clash if made pdf names,
+	// and re-registering keeps the number, so fields already named still match
+	font, ok := registeredFonts.fonts[name]
+	if !ok {
+		font.resource = fmt.Sprintf("Reg%d", len(registeredFonts.fonts)+1)
+	}
+
+	font.data = data
+
+	registeredFonts.fonts[name] = font
+
+	return nil
+}
+
+// RegisterFontFile registers the font in the file at path
+func RegisterFontFile(name, path string) error {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.New(fmt.Sprintf("can't read font %s from %s: %v", name, path, err))
+	}
+
+	return RegisterFont(name, data)
+}
+
+func registeredFont(name string) (fontFile, bool) {
+
+	registeredFonts.RLock()
+	defer registeredFonts.RUnlock()
+
+	font, ok := registeredFonts.fonts[name]
+
+	return font, ok
+}
+
+// knownFont is whether a font can be used, by being standard or registered
+func knownFont(name string) bool {
+
+	if _, ok := fieldFonts[name]; ok {
+		return true
+	}
+
+	_, ok := registeredFont(name)
+
+	return ok
+}
+
+// fieldFontName is the name a font is given in the form's resources. The
+// standard fonts have conventional names, and registered fonts are
+// numbered, as Reg1, Reg2 and so on.
+func fieldFontName(font string) string {
+
+	if name, ok := fieldFonts[font]; ok {
+		return name
+	}
+
+	if registered, ok := registeredFont(font); ok {
+		return registered.resource
+	}
+
+	return ""
+}
+
+// newFieldFont loads a font for the form's resources, in full
+func newFieldFont(font string) (*model.PdfFont, error) {
+
+	if registered, ok := registeredFont(font); ok {
+		return model.NewCompositePdfFontFromTTF(bytes.NewReader(registered.data))
+	}
+
+	if _, ok := fieldFonts[font]; !ok {
+		return nil, errors.New(fmt.Sprintf("unknown font %s", font))
+	}
+
+	return model.NewStandard14Font(model.StdFontName(font))
+}
+
+// pageFonts loads each font once for a page, so that a registered font is
+// embedded once, however many prefills use it, and subset to what they use
+type pageFonts struct {
+	c      *creator.Creator
+	loaded map[string]*model.PdfFont
+}
+
+func newPageFonts(c *creator.Creator) *pageFonts {
+	return &pageFonts{c: c, loaded: make(map[string]*model.PdfFont)}
+}
+
+func (f *pageFonts) font(name string) (*model.PdfFont, error) {
+
+	if font, ok := f.loaded[name]; ok {
+		return font, nil
+	}
+
+	font, err := newFieldFont(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := registeredFont(name); ok {
+		f.c.EnableFontSubsetting(font)
+	}
+
+	f.loaded[name] = font
+
+	return font, nil
+}
@@ -0,0 +1,225 @@
+package parsesvg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/timdrysdale/geo"
+	"github.com/timdrysdale/unipdf/v3/core"
+	"github.com/timdrysdale/unipdf/v3/model"
+)
+
+func TestRegisterFont(t *testing.T) {
+
+	for _, name := range []string{"", "Helvetica", "Times-Bold"} {
+		if err := RegisterFont(name, nil); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+
+	if err := RegisterFont("Garbage", []byte("not a font")); err == nil {
+		t.Error("Expected an error registering something that isn't a font")
+	}
+
+	if err := RegisterFontFile("Missing", "./test/no-such-font.ttf"); err == nil {
+		t.Error("Expected an error registering a missing file")
+	}
+
+	if knownFont("Garbage") || knownFont("Missing") {
+		t.Error("Fonts that failed to register shouldn't be known")
+	}
+}
+
+// squaresFont is a tiny TrueType font made for these tests, which draws a
+// square for A, B, a, b, é and Ω
+const squaresFont = "./test/squares.ttf"
+
+func TestRegisteredFontNames(t *testing.T) {
+
+	// names that differ only in spaces or punctuation are different fonts
+	for _, name := range []string{"Noto Sans-Greek", "NotoSans Greek"} {
+		if err := RegisterFontFile(name, squaresFont); err != nil {
+			t.Fatalf("Error registering %s %v", name, err)
+		}
+	}
+
+	if !knownFont("Noto Sans-Greek") || !knownFont("Courier") || knownFont("Comic Sans") {
+		t.Error("Known fonts wrong")
+	}
+
+	name := fieldFontName("Noto Sans-Greek")
+	if !strings.HasPrefix(name, "Reg") || name == fieldFontName("NotoSans Greek") {
+		t.Errorf("Got resource names %s and %s for different registered fonts", name, fieldFontName("NotoSans Greek"))
+	}
+
+	// registering again replaces the font, but keeps its name
+	if err := RegisterFontFile("Noto Sans-Greek", squaresFont); err != nil {
+		t.Fatal(err)
+	}
+
+	if again := fieldFontName("Noto Sans-Greek"); again != name {
+		t.Errorf("Resource name changed from %s to %s on registering again", name, again)
+	}
+
+	if name := fieldFontName("Courier"); name != "Cour" {
+		t.Errorf("Got resource name %s for a standard font", name)
+	}
+
+	var tf TextField
+	if err := UnmarshalTextField(&tf, `{"font":"Noto Sans-Greek"}`); err != nil {
+		t.Errorf("Unexpected error using a registered font in a textfield %v", err)
+	}
+
+	if err := (Paragraph{TextFont: "Noto Sans-Greek"}).check(); err != nil {
+		t.Errorf("Unexpected error using a registered font in a prefill %v", err)
+	}
+}
+
+// embeddedFonts are the TrueType files embedded for the fonts in a
+// resource dictionary, by resource name
+func embeddedFonts(t *testing.T, fonts core.PdfObject) map[string][]byte {
+
+	files := make(map[string][]byte)
+
+	dict, ok := core.GetDict(fonts)
+	if !ok {
+		return files
+	}
+
+	for _, key := range dict.Keys() {
+
+		font, ok := core.GetDict(dict.Get(key))
+		if !ok {
+			continue
+		}
+
+		// only composite fonts have a font file, in their descendant
+		descendants, ok := core.GetArray(font.Get("DescendantFonts"))
+		if !ok || descendants.Len() == 0 {
+			continue
+		}
+
+		descendant, ok := core.GetDict(descendants.Get(0))
+		if !ok {
+			continue
+		}
+
+		descriptor, ok := core.GetDict(descendant.Get("FontDescriptor"))
+		if !ok {
+			continue
+		}
+
+		stream, ok := core.GetStream(descriptor.Get("FontFile2"))
+		if !ok {
+			continue
+		}
+
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[string(key)] = data
+	}
+
+	return files
+}
+
+func TestRenderRegisteredFont(t *testing.T) {
+
+	err := RegisterFontFile("Squares", squaresFont)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rect := func(y float64) geo.Rect {
+		return geo.Rect{Corner: geo.Point{X: 5, Y: y}, Dim: geo.Dim{Width: 60, Height: 20}}
+	}
+
+	fields := &Ladder{
+		Dim: geo.Dim{Width: 100, Height: 160},
+		TextPrefills: []TextPrefill{
+			TextPrefill{Rect: rect(10), ID: "label", Text: Paragraph{Text: "AB", TextFont: "Squares", TextSize: 12}},
+		},
+		TextFields: []TextField{
+			TextField{Rect: rect(40), ID: "answer", TabSequence: 1, Options: TextFieldOptions{Font: "Squares", FontSize: 12}},
+		},
+	}
+
+	templates := fstest.MapFS{"designs/layout.svg": &fstest.MapFile{Data: []byte(tabOrderLayoutSVG)}}
+
+	for name, ladder := range map[string]*Ladder{"fields": fields, "more": &Ladder{Dim: geo.Dim{Width: 100, Height: 100}}} {
+		var buf bytes.Buffer
+		err := WriteLadderSVG(&buf, ladder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates["designs/"+name+".svg"] = &fstest.MapFile{Data: buf.Bytes()}
+	}
+
+	contents := SpreadContents{
+		SvgLayoutPath: "designs/layout.svg",
+		SpreadName:    "mark",
+		PageNumber:    1,
+		PdfOutputPath: "./test/render-registered-font.pdf",
+		Templates:     templates,
+		VectorChrome:  true,
+	}
+
+	err = RenderSpreadExtra(contents, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(contents.PdfOutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//avoid seg fault, obvs
+	if page.Resources == nil || reader.AcroForm == nil || reader.AcroForm.DR == nil {
+		t.Fatal("Expected page and form resources")
+	}
+
+	full, err := ioutil.ReadFile(squaresFont)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the textfield needs every character, in case the marker types it
+	field, ok := embeddedFonts(t, reader.AcroForm.DR.Font)[fieldFontName("Squares")]
+	if !ok {
+		t.Fatalf("Expected the textfield font to be embedded as %s", fieldFontName("Squares"))
+	}
+
+	if len(field) < len(full) {
+		t.Errorf("Expected the whole font in the textfield, got %d of %d bytes", len(field), len(full))
+	}
+
+	// but the prefill only needs A and B
+	prefill := embeddedFonts(t, page.Resources.Font)
+	if len(prefill) != 1 {
+		t.Fatalf("Expected one embedded font for the prefill, got %d", len(prefill))
+	}
+
+	for name, data := range prefill {
+		if len(data) == 0 || len(data) >= len(field) {
+			t.Errorf("Expected the prefill font %s to be subset, got %d bytes from %d", name, len(data), len(field))
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package parsesvg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/timdrysdale/geo"
+)
+
+// LadderStyle is the template that mark ladders are generated from. All
+// lengths are in points. It has json tags, so that it can be kept with
+// the rest of the designs.
+type LadderStyle struct {
+	ID            string   `json:"id"`      // of the ladder, numbered if there is more than one
+	Heading       string   `json:"heading"` // drawn at the top of each column
+	Kinds         []string `json:"kinds"`   // of box on each row, e.g. mark and moderate
+	Columns       int      `json:"columns"` // in each ladder, before starting another
+	ColumnWidth   float64  `json:"columnWidth"`
+	Height        float64  `json:"height"`
+	Margin        float64  `json:"margin"` // around the edge of each column
+	HeadingHeight float64  `json:"headingHeight"`
+	RowHeight     float64  `json:"rowHeight"`
+	RowGap        float64  `json:"rowGap"`
+	LabelWidth    float64  `json:"labelWidth"` // for the part names, which are right aligned
+	BoxWidth      float64  `json:"boxWidth"`
+	Gap           float64  `json:"gap"`        // between the label, the boxes, and the marks available
+	MarksWidth    float64  `json:"marksWidth"` // for the marks available, e.g. /10, after the boxes
+	FontSize      float64  `json:"fontSize"`
+	Font          string   `json:"font"` // svg font-family, drawn in the nearest standard pdf font
+	BoxColor      string   `json:"boxColor"`
+	TextColor     string   `json:"textColor"`
+}
+
+// DefaultLadderStyle is a single column of marks, the height of an A4 page
+var DefaultLadderStyle = LadderStyle{
+	ID:            "sidebar-mark",
+	Heading:       "Marks",
+	Kinds:         []string{"mark"},
+	Columns:       1,
+	ColumnWidth:   120,
+	Height:        297 * geo.PPMM,
+	Margin:        8,
+	HeadingHeight: 24,
+	RowHeight:     20,
+	RowGap:        4,
+	LabelWidth:    36,
+	BoxWidth:      30,
+	Gap:           4,
+	MarksWidth:    24,
+	FontSize:      10,
+	Font:          "sans-serif",
+	BoxColor:      "#ed1c24",
+	TextColor:     "#000000",
+}
+
+// MarkLadder is a generated ladder, and the svg that it was written to,
+// which can be opened in inkscape, or read back with DefineLadderFromSVG
+type MarkLadder struct {
+	Ladder *Ladder
+	SVG    []byte
+}
+
+// markRow is a part, or the totals, on one row of a ladder
+type markRow struct {
+	pnum  int // position in the csv, as used in the field names
+	label string
+	marks int
+	total bool
+}
+
+// GenerateMarkLadders lays out a ladder with a row for each part of the
+// paper, with its name, a box for each kind of mark, and the marks
+// available, then a row of totals. Rows that don't fit in a column go in
+// the next, and columns that don't fit in a ladder go in another ladder,
+// so that a long paper gets extra sidebars. The mark boxes are named
+// qn-part-<kind>-<n>, as for the qn-part placeholders, and the totals
+// qn-total-<kind>, so the totals only add up if all the ladders are on
+// the same page. Each kind of box is in tab order down the paper, before
+// the next kind. The chrome is attached to each ladder as vector chrome.
+// GenerateMarkLayout writes a layout that puts them all on one page.
+func GenerateMarkLadders(parts []*PaperStructure, style LadderStyle) ([]MarkLadder, error) {
+
+	var rows []markRow
+
+	total := 0
+
+	for pnum, part := range parts {
+		// blank rows in the csv give empty parts
+		if part == nil || part.Part == "" {
+			continue
+		}
+		if part.Marks < 0 {
+			return nil, errors.New(fmt.Sprintf("part %s can't have negative marks", part.Part))
+		}
+		rows = append(rows, markRow{pnum: pnum, label: part.Part, marks: part.Marks})
+		total += part.Marks
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no parts to make a ladder for")
+	}
+
+	// the total has the most marks, so the widest marks available
+	perColumn, err := style.rowsPerColumn("/" + strconv.Itoa(total))
+	if err != nil {
+		return nil, err
+	}
+
+	numParts := len(rows)
+
+	rows = append(rows, markRow{label: "Total", marks: total, total: true})
+
+	perLadder := perColumn * style.Columns
+	numLadders := (len(rows) + perLadder - 1) / perLadder
+
+	var ladders []MarkLadder
+
+	for l := 0; l < numLadders; l++ {
+
+		ladder := &Ladder{
+			ID:  style.ID,
+			Dim: geo.Dim{Width: float64(style.Columns) * style.ColumnWidth, Height: style.Height},
+		}
+
+		if numLadders > 1 {
+			ladder.ID = fmt.Sprintf("%s-%d", style.ID, l+1)
+		}
+
+		chrome := &bytes.Buffer{}
+
+		first := l * perLadder
+		last := first + perLadder
+		if last > len(rows) {
+			last = len(rows)
+		}
+
+		for i := first; i < last; i++ {
+
+			row := rows[i]
+
+			column := (i - first) / perColumn
+			x := float64(column)*style.ColumnWidth + style.Margin
+			y := style.Margin + style.HeadingHeight + float64((i-first)%perColumn)*(style.RowHeight+style.RowGap)
+
+			if (i-first)%perColumn == 0 && style.Heading != "" {
+				centre := float64(column)*style.ColumnWidth + style.ColumnWidth/2
+				style.text(chrome, centre, style.Margin+style.HeadingHeight/2, "middle", true, style.Heading)
+			}
+
+			style.text(chrome, x+style.LabelWidth, y+style.RowHeight/2, "end", row.total, row.label)
+
+			x = x + style.LabelWidth
+
+			for k, kind := range style.Kinds {
+
+				x = x + style.Gap
+
+				tf := TextField{Rect: geo.Rect{Corner: geo.Point{X: x, Y: y}, Dim: geo.Dim{Width: style.BoxWidth, Height: style.RowHeight}}}
+
+				if row.total {
+					tf.ID = "qn-total-" + kind
+					tf.TabSequence = int64(len(style.Kinds)*numParts + k + 1)
+					tf.Options = TextFieldOptions{Numeric: true, ReadOnly: true, Sum: []string{"qn-part-" + kind + "-*"}}
+				} else {
+					// only accept marks between zero and the marks for this part
+					min := 0.0
+					max := float64(row.marks)
+					tf.ID = "qn-part-" + kind + "-" + strconv.Itoa(row.pnum)
+					tf.TabSequence = int64(k*numParts + i + 1)
+					tf.Options = TextFieldOptions{Numeric: true, Min: &min, Max: &max}
+				}
+
+				ladder.TextFields = append(ladder.TextFields, tf)
+
+				fmt.Fprintf(chrome, `    <rect
+       x="%s"
+       y="%s"
+       width="%s"
+       height="%s"
+       style="fill:#ffffff;stroke:%s;stroke-width:1" />
+`, num(x), num(y), num(style.BoxWidth), num(style.RowHeight), attrEscaper.Replace(style.BoxColor))
+
+				x = x + style.BoxWidth
+			}
+
+			style.text(chrome, x+style.Gap, y+style.RowHeight/2, "start", row.total, "/"+strconv.Itoa(row.marks))
+		}
+
+		// as DefineLadderFromSVG would have them
+		sort.SliceStable(ladder.TextFields, func(i, j int) bool {
+			return ladder.TextFields[i].TabSequence < ladder.TextFields[j].TabSequence
+		})
+
+		svg := &bytes.Buffer{}
+
+		err = writeLadderSVG(svg, ladder, chrome.String())
+		if err != nil {
+			return nil, err
+		}
+
+		vector, err := DefineVectorChromeFromSVG(svg.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		ladder.Images = []ImageInsert{ImageInsert{Dim: ladder.Dim, Vector: vector}}
+
+		ladders = append(ladders, MarkLadder{Ladder: ladder, SVG: svg.Bytes()})
+	}
+
+	return ladders, nil
+}
+
+// GenerateMarkLayout writes a layout for the spread, with the script on the
+// left, in a box the size of script, and the ladders side by side to its
+// right, as sidebars, all on one page so that the totals add up. Each
+// ladder is anchored at its top left corner, titled svg-<spread>-<id>,
+// with dir/<id> as the description, so save each ladder's svg there,
+// relative to the layout if it is in Templates. The layout is in points,
+// and is titled layout-<spread>, for DefineLayoutFromSVG to read.
+func GenerateMarkLayout(spread, dir string, script geo.Dim, ladders []MarkLadder) ([]byte, error) {
+
+	if spread == "" {
+		return nil, errors.New("layout needs a spread name")
+	}
+
+	if len(ladders) == 0 {
+		return nil, errors.New("no ladders to make a layout for")
+	}
+
+	page := script
+
+	for _, ml := range ladders {
+		if ml.Ladder == nil { //avoid seg fault, obvs
+			return nil, errors.New("nil pointer to ladder")
+		}
+		page.Width = page.Width + ml.Ladder.Dim.Width
+		page.Height = math.Max(page.Height, ml.Ladder.Dim.Height)
+	}
+
+	s := &svgWriter{}
+
+	s.header("layout-"+spread, page)
+
+	s.startLayer(geo.PagesLayer)
+	s.rect("page-1", "page-"+spread, "", geo.Rect{Dim: page})
+	s.endLayer()
+
+	s.startLayer(geo.ImagesLayer)
+	s.rect("image-1", "image-previous-"+spread, "", geo.Rect{Dim: script})
+	s.endLayer()
+
+	s.startLayer(geo.AnchorsLayer)
+	s.anchor(geo.Point{})
+	s.namedAnchor("anchor-1", "img-previous-"+spread, "", geo.Point{})
+
+	x := script.Width
+
+	for i, ml := range ladders {
+		s.namedAnchor(fmt.Sprintf("anchor-%d", i+2), geo.SVGElement+spread+"-"+ml.Ladder.ID, path.Join(dir, ml.Ladder.ID), geo.Point{X: x})
+		x = x + ml.Ladder.Dim.Width
+	}
+
+	s.endLayer()
+
+	s.printf("</svg>\n")
+
+	return s.buf.Bytes(), nil
+}
+
+// digitWidth is the width of a digit, or the slash, as a fraction of the
+// font size, as it is for Helvetica, which is drawn for sans-serif
+const digitWidth = 0.556
+
+// rowsPerColumn checks that the style leaves room for at least one row,
+// and that a row fits across a column, including the widest marks text
+func (style LadderStyle) rowsPerColumn(widestMarks string) (int, error) {
+
+	if len(style.Kinds) == 0 {
+		return 0, errors.New("ladder style needs at least one kind of box")
+	}
+
+	if style.Columns < 1 {
+		return 0, errors.New("ladder style needs at least one column")
+	}
+
+	if style.RowHeight <= 0 || style.BoxWidth <= 0 || style.FontSize <= 0 {
+		return 0, errors.New("ladder style needs a positive row height, box width and font size")
+	}
+
+	marksWidth := math.Max(style.MarksWidth, float64(len(widestMarks))*digitWidth*style.FontSize)
+
+	across := 2*style.Margin + style.LabelWidth + float64(len(style.Kinds))*(style.Gap+style.BoxWidth) + style.Gap + marksWidth
+	if across > style.ColumnWidth {
+		return 0, errors.New(fmt.Sprintf("ladder style columns must be at least %s wide to fit a row and its marks", num(across)))
+	}
+
+	down := style.Height - 2*style.Margin - style.HeadingHeight
+
+	rows := int((down + style.RowGap) / (style.RowHeight + style.RowGap))
+	if rows < 1 {
+		return 0, errors.New("ladder style is not tall enough for a row")
+	}
+
+	return rows, nil
+}
+
+// text writes a line of text to the chrome, centred vertically on y
+func (style LadderStyle) text(w *bytes.Buffer, x, y float64, anchor string, bold bool, str string) {
+
+	weight := "normal"
+	if bold {
+		weight = "bold"
+	}
+
+	fmt.Fprintf(w, `    <text
+       x="%s"
+       y="%s"
+       style="font-size:%s;font-family:%s;font-weight:%s;text-anchor:%s;fill:%s">%s</text>
+`, num(x), num(y+0.35*style.FontSize), num(style.FontSize), attrEscaper.Replace(style.Font), weight, anchor, attrEscaper.Replace(style.TextColor), escape(str))
+}
@@ -0,0 +1,278 @@
+package parsesvg
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestGenerateMarkLadders(t *testing.T) {
+
+	parts := []*PaperStructure{
+		&PaperStructure{Part: "1a", Marks: 3},
+		&PaperStructure{Part: "1b", Marks: 5},
+		&PaperStructure{}, // blank row in the csv
+		&PaperStructure{Part: "2", Marks: 10},
+	}
+
+	style := DefaultLadderStyle
+	style.Kinds = []string{"mark", "moderate"}
+	style.ColumnWidth = 160
+
+	ladders, err := GenerateMarkLadders(parts, style)
+	if err != nil {
+		t.Fatalf("Error generating ladders %v", err)
+	}
+
+	if len(ladders) != 1 {
+		t.Fatalf("Expected one ladder, got %d", len(ladders))
+	}
+
+	ladder := ladders[0].Ladder
+
+	// each kind in turn down the paper, then the totals
+	want := []string{"qn-part-mark-0", "qn-part-mark-1", "qn-part-mark-3",
+		"qn-part-moderate-0", "qn-part-moderate-1", "qn-part-moderate-3",
+		"qn-total-mark", "qn-total-moderate"}
+
+	var got []string
+	for i, tf := range ladder.TextFields {
+		got = append(got, tf.ID)
+		if tf.TabSequence != int64(i+1) {
+			t.Errorf("%s has tab sequence %d", tf.ID, tf.TabSequence)
+		}
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Fields wrong\n%v\n%v", want, got)
+	}
+
+	if *ladder.TextFields[2].Options.Max != 10 || ladder.TextFields[0].Rect.Corner.Y != ladder.TextFields[3].Rect.Corner.Y {
+		t.Errorf("Mark box for part 2 wrong %v", ladder.TextFields[2])
+	}
+
+	total := ladder.TextFields[7]
+	if !total.Options.ReadOnly || !reflect.DeepEqual(total.Options.Sum, []string{"qn-part-moderate-*"}) {
+		t.Errorf("Total wrong %v", total)
+	}
+
+	if len(ladder.Images) != 1 || ladder.Images[0].Vector == nil {
+		t.Fatalf("Expected vector chrome")
+	}
+
+	var texts []string
+	for _, item := range ladder.Images[0].Vector.Items {
+		if item.Text != nil {
+			texts = append(texts, item.Text.Text)
+		}
+	}
+
+	if !reflect.DeepEqual(texts, []string{"Marks", "1a", "/3", "1b", "/5", "2", "/10", "Total", "/18"}) {
+		t.Errorf("Chrome text wrong %v", texts)
+	}
+
+	// the svg reads back as the same ladder, apart from the chrome
+	readBack, err := DefineLadderFromSVG(ladders[0].SVG)
+	if err != nil {
+		t.Fatalf("Error reading generated svg %v", err)
+	}
+
+	ladder.Images = nil
+
+	if !reflect.DeepEqual(ladder, readBack) {
+		t.Errorf("Generated svg reads back differently\n%v\n%v", ladder, readBack)
+	}
+}
+
+func TestGenerateMarkLaddersPaginate(t *testing.T) {
+
+	var parts []*PaperStructure
+	for i := 0; i < 80; i++ {
+		parts = append(parts, &PaperStructure{Part: fmt.Sprintf("%d", i+1), Marks: 1})
+	}
+
+	// 33 rows fit in a column, so 81 rows need three sidebars, or two
+	// sidebars of two columns
+	tests := []struct {
+		columns int
+		ladders int
+		width   float64
+	}{
+		{1, 3, 120},
+		{2, 2, 240},
+	}
+
+	for _, test := range tests {
+
+		style := DefaultLadderStyle
+		style.Columns = test.columns
+
+		ladders, err := GenerateMarkLadders(parts, style)
+		if err != nil {
+			t.Fatalf("Error generating ladders %v", err)
+		}
+
+		if len(ladders) != test.ladders {
+			t.Errorf("Expected %d ladders, got %d", test.ladders, len(ladders))
+			continue
+		}
+
+		last := ladders[len(ladders)-1].Ladder
+		if last.ID != fmt.Sprintf("sidebar-mark-%d", test.ladders) || last.Dim.Width != test.width {
+			t.Errorf("Last ladder wrong %s %v", last.ID, last.Dim)
+		}
+
+		if last.TextFields[len(last.TextFields)-1].ID != "qn-total-mark" {
+			t.Errorf("Expected the total at the end of the last ladder")
+		}
+
+		for _, tf := range last.TextFields {
+			if tf.Rect.Corner.Y+tf.Rect.Dim.Height > last.Dim.Height {
+				t.Errorf("%s runs off the page", tf.ID)
+			}
+		}
+	}
+
+	style := DefaultLadderStyle
+	style.ColumnWidth = 50
+
+	_, err := GenerateMarkLadders(parts, style)
+	if err == nil {
+		t.Error("Expected an error for a column too narrow for a row")
+	}
+
+	_, err = GenerateMarkLadders([]*PaperStructure{&PaperStructure{}}, DefaultLadderStyle)
+	if err == nil {
+		t.Error("Expected an error for no parts")
+	}
+}
+
+func TestGenerateMarkLaddersMarksWidth(t *testing.T) {
+
+	parts := []*PaperStructure{&PaperStructure{Part: "1", Marks: 10}}
+
+	// wide enough for the label and box, but not the marks after them
+	style := DefaultLadderStyle
+	style.ColumnWidth = 100
+
+	_, err := GenerateMarkLadders(parts, style)
+	if err == nil {
+		t.Error("Expected an error for a column with no room for the marks")
+	}
+
+	// exactly wide enough
+	style.ColumnWidth = 2*8 + 36 + 4 + 30 + 4 + 24
+
+	ladders, err := GenerateMarkLadders(parts, style)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// the marks are drawn inside the column, whatever their width
+	found := false
+	for _, item := range ladders[0].Ladder.Images[0].Vector.Items {
+		if item.Text != nil && item.Text.Text == "/10" {
+			found = true
+			end := item.Text.Position.X + float64(len(item.Text.Text))*digitWidth*style.FontSize
+			if end > style.ColumnWidth-style.Margin {
+				t.Errorf("Marks text ends at %g, past the margin", end)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected the marks to be drawn")
+	}
+
+	// more marks than the style's width allows for need a wider column
+	style.MarksWidth = 0
+	parts[0].Marks = 100000
+
+	_, err = GenerateMarkLadders(parts, style)
+	if err == nil {
+		t.Error("Expected an error for a total too wide for the column")
+	}
+}
+
+func TestGenerateMarkLayout(t *testing.T) {
+
+	var parts []*PaperStructure
+	for i := 0; i < 40; i++ {
+		parts = append(parts, &PaperStructure{Part: fmt.Sprintf("%d", i+1), Marks: 1})
+	}
+
+	ladders, err := GenerateMarkLadders(parts, DefaultLadderStyle)
+	if err != nil {
+		t.Fatalf("Error generating ladders %v", err)
+	}
+
+	if len(ladders) != 2 {
+		t.Fatalf("Expected two ladders, got %d", len(ladders))
+	}
+
+	script := geo.Dim{Width: 595, Height: 842}
+
+	svg, err := GenerateMarkLayout("mark", "sidebars", script, ladders)
+	if err != nil {
+		t.Fatalf("Error generating layout %v", err)
+	}
+
+	layout, err := DefineLayoutFromSVG(svg)
+	if err != nil {
+		t.Fatalf("Error reading generated layout %v", err)
+	}
+
+	// the sidebars go to the right of the script, side by side
+	wantAnchors := map[string]geo.Point{
+		"img-previous-mark":       geo.Point{X: 0, Y: 0},
+		"svg-mark-sidebar-mark-1": geo.Point{X: 595, Y: 0},
+		"svg-mark-sidebar-mark-2": geo.Point{X: 715, Y: 0},
+	}
+	if !reflect.DeepEqual(layout.Anchors, wantAnchors) {
+		t.Errorf("Anchors wrong %v", layout.Anchors)
+	}
+
+	wantFilenames := map[string]string{
+		"svg-mark-sidebar-mark-1": "sidebars/sidebar-mark-1",
+		"svg-mark-sidebar-mark-2": "sidebars/sidebar-mark-2",
+	}
+	if !reflect.DeepEqual(layout.Filenames, wantFilenames) {
+		t.Errorf("Filenames wrong %v", layout.Filenames)
+	}
+
+	if layout.ID != "layout-mark" || layout.PageDims["mark"] != (geo.Dim{Width: 835, Height: 842}) || layout.ImageDims["previous-mark"] != script {
+		t.Errorf("Layout wrong %v", layout)
+	}
+
+	// and it renders, with the ladders saved where the layout expects them
+	templates := fstest.MapFS{"designs/layout.svg": &fstest.MapFile{Data: svg}}
+	for _, ml := range ladders {
+		templates["designs/sidebars/"+ml.Ladder.ID+".svg"] = &fstest.MapFile{Data: ml.SVG}
+	}
+
+	contents := SpreadContents{
+		SvgLayoutPath: "designs/layout.svg",
+		SpreadName:    "mark",
+		PageNumber:    1,
+		PdfOutputPath: "./test/render-generated-layout.pdf",
+		Templates:     templates,
+		VectorChrome:  true,
+	}
+
+	err = RenderSpreadExtra(contents, parts)
+	if err != nil {
+		t.Errorf("Error rendering generated layout %v", err)
+	}
+
+	_, err = GenerateMarkLayout("", "", script, ladders)
+	if err == nil {
+		t.Error("Expected an error for no spread name")
+	}
+
+	_, err = GenerateMarkLayout("mark", "", script, nil)
+	if err == nil {
+		t.Error("Expected an error for no ladders")
+	}
+}
@@ -0,0 +1,163 @@
+package parsesvg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"strings"
+
+	"github.com/timdrysdale/unipdf/v3/creator"
+	_ "golang.org/x/image/tiff"
+)
+
+var ErrMissingHref = errors.New("image has no href")
+
+// The extensions tried, in order, for an image named without one. Chrome
+// has always been exported as png, and other images converted to jpg, so
+// those come first to find the same files as before.
+var (
+	chromeExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".tif", ".tiff"}
+	imageExtensions  = []string{".jpg", ".jpeg", ".png", ".gif", ".tif", ".tiff"}
+)
+
+func isImageExtension(ext string) bool {
+	for _, known := range imageExtensions {
+		if strings.EqualFold(ext, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// newImageFromData passes jpegs straight through, as the pdf can hold them
+// as they are, but decodes anything else, e.g. png, gif or tiff.
+func newImageFromData(c *creator.Creator, data []byte) (*creator.Image, error) {
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "jpeg" {
+		return c.NewImageFromData(data)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.NewImageFromGoImage(flattenOnWhite(img))
+}
+
+// flattenOnWhite composites a transparent image onto white, which is what
+// exporting with a white background layer used to do by hand
+func flattenOnWhite(img image.Image) image.Image {
+
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+
+	draw.Draw(flat, bounds, &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+
+	return flat
+}
+
+// getImageInsert places an <image> element where it is drawn, reading its
+// contents from a data: URI, or noting its filename for later
+func getImageInsert(im *Cimage__svg, ctm Matrix) (ImageInsert, error) {
+
+	insert := ImageInsert{}
+
+	// an image is placed the same way as a rect, so reuse that
+	rect, err := getRect(&Crect__svg{
+		Id:        im.Id,
+		Rx:        im.Rx,
+		Ry:        im.Ry,
+		Width:     im.Width,
+		Height:    im.Height,
+		Transform: im.Transform,
+	}, ctm)
+	if err != nil {
+		return insert, err
+	}
+
+	insert.Corner = rect.Corner
+	insert.Dim = rect.Dim
+
+	href := im.Href
+	if href == "" {
+		href = im.XlinkHref
+	}
+
+	insert.Filename, insert.Data, err = parseHref(href)
+	if err != nil {
+		return insert, attributeError(im.Id, "href", href, err)
+	}
+
+	return insert, nil
+}
+
+// parseHref returns either the filename, or the data, that an image
+// refers to. A relative filename is left as it is, so it can be found
+// in the same way as the chrome images.
+func parseHref(href string) (string, []byte, error) {
+
+	href = strings.TrimSpace(href)
+
+	switch {
+
+	case href == "":
+		return "", nil, ErrMissingHref
+
+	case strings.HasPrefix(href, "data:"):
+		data, err := decodeDataURI(href)
+		return "", data, err
+
+	case strings.HasPrefix(href, "file:"):
+		u, err := url.Parse(href)
+		if err != nil {
+			return "", nil, err
+		}
+		return u.Path, nil, nil
+	}
+
+	return href, nil, nil
+}
+
+// decodeDataURI takes the data from a data: URI, in base64 as inkscape
+// embeds it, or percent-encoded. Inkscape wraps long base64 lines, so
+// whitespace is ignored.
+func decodeDataURI(uri string) ([]byte, error) {
+
+	comma := strings.Index(uri, ",")
+	if comma < 0 {
+		return nil, errors.New("data URI has no comma before the data")
+	}
+
+	mediatype := uri[len("data:"):comma]
+	payload := uri[comma+1:]
+
+	if strings.HasSuffix(mediatype, ";base64") {
+		payload = strings.Join(strings.Fields(payload), "")
+		return base64.StdEncoding.DecodeString(payload)
+	}
+
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(data), nil
+}
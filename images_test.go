@@ -0,0 +1,215 @@
+package parsesvg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/timdrysdale/geo"
+	"github.com/timdrysdale/unipdf/v3/creator"
+	"golang.org/x/image/tiff"
+)
+
+func TestParseHref(t *testing.T) {
+
+	tests := []struct {
+		href     string
+		filename string
+		data     []byte
+	}{
+		{"logo.png", "logo.png", nil},
+		{"./test/logo.png", "./test/logo.png", nil},
+		{"file:///home/designer/logo.png", "/home/designer/logo.png", nil},
+		{"data:image/png;base64,aGVs\n   bG8=", "", []byte("hello")},
+		{"data:,hello%20there", "", []byte("hello there")},
+	}
+
+	for _, test := range tests {
+
+		filename, data, err := parseHref(test.href)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.href, err)
+		}
+		if filename != test.filename || !reflect.DeepEqual(data, test.data) {
+			t.Errorf("%s: got %q %q", test.href, filename, data)
+		}
+	}
+
+	_, _, err := parseHref("")
+	if !errors.Is(err, ErrMissingHref) {
+		t.Errorf("Expected ErrMissingHref, got %v", err)
+	}
+
+	_, _, err = parseHref("data:image/png;base64,!!!")
+	if err == nil {
+		t.Error("Expected an error for bad base64")
+	}
+}
+
+var imagesSVG = inkscapeSVG(fixturePage{Width: "100mm", Height: "100mm", ViewBox: "0 0 100 100", Units: "mm"}, `
+  <g
+     inkscape:label="images"
+     inkscape:groupmode="layer"
+     transform="translate(5,0)"
+     id="layer1">
+    <image
+       id="image1"
+       x="10"
+       y="20"
+       width="30"
+       height="15"
+       xlink:href="logo.png">
+      <title>image-mark-logo</title>
+    </image>
+    <image
+       id="image2"
+       x="0"
+       y="0"
+       width="10"
+       height="10"
+       href="data:image/png;base64,aGVsbG8=">
+      <title>image-mark-box</title>
+    </image>
+  </g>
+`)
+
+func TestDefineLayoutImages(t *testing.T) {
+
+	layout, err := DefineLayoutFromSVG([]byte(imagesSVG))
+	if err != nil {
+		t.Fatalf("Error defining layout %v", err)
+	}
+
+	mm := geo.PPMM // not a constant, so the products round as in the parser
+
+	want := map[string]ImageInsert{
+		"image-mark-logo": ImageInsert{
+			Filename: "logo.png",
+			Corner:   geo.Point{X: 15 * mm, Y: 20 * mm},
+			Dim:      geo.Dim{Width: 30 * mm, Height: 15 * mm},
+		},
+		"image-mark-box": ImageInsert{
+			Data:   []byte("hello"),
+			Corner: geo.Point{X: 5 * mm, Y: 0},
+			Dim:    geo.Dim{Width: 10 * mm, Height: 10 * mm},
+		},
+	}
+
+	if !reflect.DeepEqual(want, layout.Images) {
+		t.Errorf("Images wrong\n%v\n%v", want, layout.Images)
+	}
+
+	ladder, err := DefineLadderFromSVG([]byte(imagesSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	if len(ladder.Images) != 2 || !reflect.DeepEqual(ladder.Images[0], want["image-mark-logo"]) {
+		t.Errorf("Ladder images wrong %v", ladder.Images)
+	}
+}
+
+func TestFindImage(t *testing.T) {
+
+	templates := templateSource{
+		fsys: fstest.MapFS{
+			"designs/chrome.png":    &fstest.MapFile{},
+			"designs/chrome.jpg":    &fstest.MapFile{},
+			"designs/logo.gif":      &fstest.MapFile{},
+			"designs/header.v2.jpg": &fstest.MapFile{},
+			"designs/scan.tif":      &fstest.MapFile{},
+		},
+		dir: "designs",
+	}
+
+	tests := []struct {
+		name       string
+		extensions []string
+		want       string
+	}{
+		{"chrome", chromeExtensions, "designs/chrome.png"},
+		{"chrome", imageExtensions, "designs/chrome.jpg"},
+		{"logo", imageExtensions, "designs/logo.gif"},
+		{"logo.gif", chromeExtensions, "designs/logo.gif"},
+		{"header.v2", imageExtensions, "designs/header.v2.jpg"},
+		{"scan", chromeExtensions, "designs/scan.tif"},
+		{"missing", imageExtensions, "designs/missing.jpg"},
+	}
+
+	for _, test := range tests {
+		got := templates.findImage(test.name, test.extensions)
+		if got != test.want {
+			t.Errorf("%s: expected %s, got %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestTemplateSourceImage(t *testing.T) {
+
+	templates := templateSource{fsys: fstest.MapFS{}, dir: "designs"}
+
+	// a linked image is relative to the design, in the templates
+	got := templates.image(ImageInsert{Filename: "logo.png"})
+	if got.Filename != "designs/logo.png" || got.FS == nil {
+		t.Errorf("Relative image wrong %v", got)
+	}
+
+	// but a file:// link is to this machine's disk
+	filename, _, err := parseHref("file:///home/marker/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = templates.image(ImageInsert{Filename: filename})
+	if got.Filename != "/home/marker/logo.png" || got.FS != nil {
+		t.Errorf("Absolute image wrong %v", got)
+	}
+
+	got = templates.image(ImageInsert{Data: []byte("embedded")})
+	if got.Filename != "" || got.FS != nil {
+		t.Errorf("Embedded image wrong %v", got)
+	}
+}
+
+func TestFlattenOnWhite(t *testing.T) {
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 0})
+	img.Set(1, 0, color.NRGBA{R: 255, A: 128})
+
+	flat := flattenOnWhite(img)
+
+	if got := color.RGBAModel.Convert(flat.At(0, 0)); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Clear pixel should be white, got %v", got)
+	}
+	if got := color.RGBAModel.Convert(flat.At(1, 0)).(color.RGBA); got.R != 255 || got.G != 127 || got.A != 255 {
+		t.Errorf("Half clear red pixel should be pink, got %v", got)
+	}
+
+	opaque := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	opaque.Set(0, 0, color.Black)
+	if flattenOnWhite(opaque) != image.Image(opaque) {
+		t.Error("Opaque image should be passed through")
+	}
+
+	_, err := newImageFromData(nil, []byte("not an image"))
+	if err == nil {
+		t.Error("Expected an error for data that isn't an image")
+	}
+
+	// tiffs are found by extension, so they must be readable too
+	var tif bytes.Buffer
+	err = tiff.Encode(&tif, opaque, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = newImageFromData(creator.New(), tif.Bytes())
+	if err != nil {
+		t.Errorf("Error reading a tiff %v", err)
+	}
+}
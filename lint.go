@@ -0,0 +1,485 @@
+package parsesvg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+)
+
+// Severity says how much a finding matters. Errors will stop a ladder or
+// layout working as intended, whereas warnings are probably mistakes.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// rules that ValidateLadder and ValidateLayout check
+const (
+	RuleParse            = "parse"
+	RuleReferenceAnchor  = "ref-anchor"
+	RuleUntitled         = "untitled"
+	RuleDuplicateTitle   = "duplicate-title"
+	RuleRadioTitle       = "radio-title"
+	RuleTextSize         = "text-size"
+	RuleTextStyle        = "text-style"
+	RuleOutsidePage      = "outside-page"
+	RuleOverlap          = "overlap"
+	RuleAnchorWithoutBox = "anchor-without-image"
+)
+
+// Finding is one problem found by ValidateLadder or ValidateLayout, with
+// enough detail to find the element in inkscape
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Layer    string
+	ID       string
+	Title    string
+	Message  string
+}
+
+func (f Finding) String() string {
+
+	where := []string{}
+
+	if f.Layer != "" {
+		where = append(where, fmt.Sprintf("layer %s", f.Layer))
+	}
+	if f.ID != "" {
+		where = append(where, fmt.Sprintf("element %s", f.ID))
+	}
+	if f.Title != "" {
+		where = append(where, fmt.Sprintf("titled %q", f.Title))
+	}
+
+	if len(where) == 0 {
+		return fmt.Sprintf("%s [%s] %s", f.Severity, f.Rule, f.Message)
+	}
+
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Rule, strings.Join(where, ", "), f.Message)
+}
+
+// lintElement is a shape from one of the layers we check, with its box
+// in points, on the page
+type lintElement struct {
+	Layer string
+	ID    string
+	Title string
+	Rect  geo.Rect
+}
+
+type linter struct {
+	findings []Finding
+}
+
+func (l *linter) add(severity Severity, rule string, e lintElement, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Severity: severity,
+		Rule:     rule,
+		Layer:    e.Layer,
+		ID:       e.ID,
+		Title:    e.Title,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) parseError(err error) {
+	l.findings = append(l.findings, Finding{Severity: SeverityError, Rule: RuleParse, Message: err.Error()})
+}
+
+func titleString(title *Ctitle__svg) string {
+	if title == nil { //avoid seg fault, obvs
+		return ""
+	}
+	return title.String
+}
+
+// ValidateLadder checks a ladder for the mistakes that don't stop it being
+// parsed, but do stop it working as intended in the pdf, e.g. duplicate
+// field titles, which Adobe Reader silently makes non-editable
+func ValidateLadder(svg *Csvg__svg) []Finding {
+
+	l := &linter{}
+
+	dim, err := getLadderDim(svg)
+	if err != nil {
+		l.parseError(err)
+		return l.findings
+	}
+
+	sf, err := getUserUnitTransform(svg)
+	if err != nil {
+		l.parseError(err)
+		return l.findings
+	}
+
+	groups, err := getLayerGroups(svg)
+	if err != nil {
+		l.parseError(err)
+		return l.findings
+	}
+
+	// the ladder takes its reference anchor from any layer
+	var anchors []lintElement
+	for _, g := range groups {
+		for _, p := range g.Cpath__svg {
+			if titleString(p.Title) == geo.AnchorReference {
+				anchors = append(anchors, lintElement{Layer: g.Layer, ID: p.ID, Title: geo.AnchorReference})
+			}
+		}
+	}
+	l.checkReferenceAnchor(anchors)
+
+	var fields []lintElement
+	names := make(map[string]lintElement)
+	radios := make(map[string]lintElement)
+
+	addField := func(e lintElement, name string) {
+		fields = append(fields, e)
+		if other, ok := names[name]; ok {
+			l.add(SeverityError, RuleDuplicateTitle, e, "has the same field name as element %s on layer %s, so neither will be editable in some viewers", other.ID, other.Layer)
+			return
+		}
+		names[name] = e
+	}
+
+	for _, g := range groups {
+
+		switch g.Layer {
+
+		case geo.TextFieldsLayer, CheckBoxesLayer, DropDownsLayer, ComboBoxesLayer:
+			for _, r := range g.Crect__svg {
+				e, ok := l.rectElement(g, r, sf)
+				if !ok {
+					continue
+				}
+				if e.Title == "" {
+					l.add(SeverityError, RuleUntitled, e, "has no title, so the field has no name")
+					continue
+				}
+				addField(e, e.Title)
+			}
+
+		case RadioButtonsLayer:
+			var buttons []lintElement
+			for _, r := range g.Crect__svg {
+				if e, ok := l.rectElement(g, r, sf); ok {
+					buttons = append(buttons, e)
+				}
+			}
+			for _, c := range g.Ccircle__svg {
+				rect, err := getCircleRect(c, g.CTM)
+				if err != nil {
+					l.parseError(elementError(err, g.Layer, c.Id, c.Title))
+					continue
+				}
+				buttons = append(buttons, lintElement{Layer: g.Layer, ID: c.Id, Title: titleString(c.Title), Rect: sf.ApplyRect(rect)})
+			}
+			for _, p := range g.Cpath__svg {
+				if p.AttrSodipodiSpacetype != "arc" {
+					continue
+				}
+				rect, err := getArcRect(p, g.CTM)
+				if err != nil {
+					l.parseError(elementError(err, g.Layer, p.ID, p.Title))
+					continue
+				}
+				buttons = append(buttons, lintElement{Layer: g.Layer, ID: p.ID, Title: titleString(p.Title), Rect: sf.ApplyRect(rect)})
+			}
+			for _, e := range buttons {
+				match := radioTitle.FindStringSubmatch(e.Title)
+				if match == nil {
+					l.add(SeverityError, RuleRadioTitle, e, "must be titled <group>-radio-<value>")
+					continue
+				}
+				if other, ok := radios[e.Title]; ok {
+					l.add(SeverityError, RuleDuplicateTitle, e, "has the same group and value as element %s", other.ID)
+					continue
+				}
+				radios[e.Title] = e
+				fields = append(fields, e)
+				// all the buttons in a group share one field name
+				if other, ok := names[match[1]]; ok && other.Layer != RadioButtonsLayer {
+					l.add(SeverityError, RuleDuplicateTitle, e, "has the same field name as element %s on layer %s, so neither will be editable in some viewers", other.ID, other.Layer)
+				} else if !ok {
+					names[match[1]] = e
+				}
+			}
+
+		case geo.TextPrefillsLayer:
+			for _, r := range g.Crect__svg {
+				e, ok := l.rectElement(g, r, sf)
+				if !ok {
+					continue
+				}
+				if e.Title == "" {
+					l.add(SeverityWarning, RuleUntitled, e, "has no title, so it can't be given text when rendering")
+				}
+				l.checkTextSize(e, r.Desc)
+			}
+
+		case "placeholders":
+			for _, r := range g.Crect__svg {
+				e, ok := l.rectElement(g, r, sf)
+				if !ok {
+					continue
+				}
+				if e.Title == "" {
+					l.add(SeverityWarning, RuleUntitled, e, "has no title, so it will never be used")
+				}
+				if r.Desc != nil {
+					_, _, err := UnmarshalPlaceholder(TextField{ID: e.Title, Prefill: r.Desc.String})
+					if err != nil {
+						l.add(SeverityError, RuleParse, e, "%v", err)
+					}
+				}
+			}
+		}
+	}
+
+	page := geo.Rect{Dim: dim}
+
+	for _, e := range fields {
+		if !rectInside(e.Rect, page) {
+			l.add(SeverityWarning, RuleOutsidePage, e, "is not completely on the page")
+		}
+	}
+
+	for i, e := range fields {
+		for _, other := range fields[i+1:] {
+			if rectsOverlap(e.Rect, other.Rect) {
+				l.add(SeverityWarning, RuleOverlap, e, "overlaps element %s on layer %s, so one will be hard to click", other.ID, other.Layer)
+			}
+		}
+	}
+
+	return l.findings
+}
+
+// ValidateLayout checks a layout for the mistakes that don't stop it being
+// parsed, but will stop a spread from rendering, or put things in the
+// wrong place
+func ValidateLayout(svg *Csvg__svg) []Finding {
+
+	l := &linter{}
+
+	_, err := getLadderDim(svg)
+	if err != nil {
+		l.parseError(err)
+		return l.findings
+	}
+
+	sf, err := getUserUnitTransform(svg)
+	if err != nil {
+		l.parseError(err)
+		return l.findings
+	}
+
+	groups, err := getLayerGroups(svg)
+	if err != nil {
+		l.parseError(err)
+		return l.findings
+	}
+
+	var references []lintElement
+	var anchors []lintElement
+
+	// only anchors with a filename in their description have an image
+	hasFile := make(map[string]bool)
+
+	for _, g := range groups {
+		if g.Layer != geo.AnchorsLayer {
+			continue
+		}
+		for _, p := range g.Cpath__svg {
+			e := lintElement{Layer: g.Layer, ID: p.ID, Title: titleString(p.Title)}
+			switch e.Title {
+			case geo.AnchorReference:
+				references = append(references, e)
+			case "":
+				l.add(SeverityWarning, RuleUntitled, e, "has no title, so nothing can be placed at it")
+			default:
+				anchors = append(anchors, e)
+				if p.Desc != nil && strings.TrimSpace(p.Desc.String) != "" {
+					hasFile[e.ID] = true
+				}
+			}
+		}
+	}
+
+	l.checkReferenceAnchor(references)
+
+	images := make(map[string]lintElement)
+	seen := make(map[string]lintElement)
+
+	for _, g := range groups {
+
+		if g.Layer != geo.PagesLayer && g.Layer != geo.ImagesLayer {
+			continue
+		}
+
+		for _, r := range g.Crect__svg {
+
+			e, ok := l.rectElement(g, r, sf)
+			if !ok {
+				continue
+			}
+
+			if e.Title == "" {
+				l.add(SeverityWarning, RuleUntitled, e, "has no title, so will be ignored")
+				continue
+			}
+
+			key := g.Layer + "/" + e.Title
+			if other, ok := seen[key]; ok {
+				l.add(SeverityError, RuleDuplicateTitle, e, "has the same title as element %s, so only one will be used", other.ID)
+				continue
+			}
+			seen[key] = e
+
+			if g.Layer == geo.ImagesLayer {
+				images[imageName(e.Title)] = e
+			}
+		}
+
+		if g.Layer != geo.ImagesLayer {
+			continue
+		}
+
+		// images placed directly in the layout need a title to pick the spread
+		for _, im := range g.Cimage__svg {
+
+			e := lintElement{Layer: g.Layer, ID: im.Id, Title: titleString(im.Title)}
+
+			_, err := getImageInsert(im, g.CTM)
+			if err != nil {
+				l.parseError(elementError(err, g.Layer, im.Id, im.Title))
+				continue
+			}
+
+			if e.Title == "" {
+				l.add(SeverityWarning, RuleUntitled, e, "has no title, so will be ignored")
+			}
+		}
+	}
+
+	names := make(map[string]lintElement)
+
+	for _, e := range anchors {
+
+		if other, ok := names[e.Title]; ok {
+			l.add(SeverityError, RuleDuplicateTitle, e, "has the same title as anchor %s, so only one will be used", other.ID)
+			continue
+		}
+		names[e.Title] = e
+
+		// ladders bring their own size, but images need a box
+		if strings.HasPrefix(e.Title, geo.SVGElement) {
+			continue
+		}
+
+		// the previous image anchor is img-previous-<spread>, but its
+		// box is previous-<spread>, as expected by RenderSpreadExtra
+		name := e.Title
+		if strings.HasPrefix(name, "img-previous-") {
+			name = strings.TrimPrefix(name, "img-")
+		} else if !hasFile[e.ID] {
+			continue
+		}
+
+		if _, ok := images[name]; !ok {
+			l.add(SeverityError, RuleAnchorWithoutBox, e, "has no box on the %s layer titled to match, so the image has no size", geo.ImagesLayer)
+		}
+	}
+
+	return l.findings
+}
+
+func (l *linter) checkReferenceAnchor(anchors []lintElement) {
+
+	switch len(anchors) {
+	case 0:
+		l.add(SeverityWarning, RuleReferenceAnchor, lintElement{}, "there is no %s, so positions will be measured from the page corner", geo.AnchorReference)
+	case 1:
+		// just right
+	default:
+		for _, e := range anchors[1:] {
+			l.add(SeverityError, RuleReferenceAnchor, e, "is one of %d reference anchors, so it's not clear which will be used", len(anchors))
+		}
+	}
+}
+
+// checkTextSize makes sure a prefill will be visible, because a missing
+// textSize means the text is drawn at size zero, and that its style can
+// be drawn
+func (l *linter) checkTextSize(e lintElement, desc *Cdesc__svg) {
+
+	if desc == nil || strings.TrimSpace(desc.String) == "" {
+		l.add(SeverityError, RuleTextSize, e, "has no description, so has no textSize")
+		return
+	}
+
+	var paragraph Paragraph
+	err := json.Unmarshal([]byte(desc.String), &paragraph)
+	if err != nil {
+		l.add(SeverityError, RuleParse, e, "description is not valid JSON: %v", err)
+		return
+	}
+
+	if paragraph.TextSize <= 0 {
+		l.add(SeverityError, RuleTextSize, e, "has no textSize in its description, so the text won't be seen")
+	}
+
+	err = paragraph.check()
+	if err != nil {
+		l.add(SeverityError, RuleTextStyle, e, "can't be drawn as described: %v", err)
+	}
+}
+
+// rectElement gets the box of a rect in points, reporting any problem
+// parsing it, in which case ok is false
+func (l *linter) rectElement(g layerGroup, r *Crect__svg, sf Matrix) (lintElement, bool) {
+
+	e := lintElement{Layer: g.Layer, ID: r.Id, Title: titleString(r.Title)}
+
+	rect, err := getRect(r, g.CTM)
+	if err != nil {
+		l.parseError(elementError(err, g.Layer, r.Id, r.Title))
+		return e, false
+	}
+
+	e.Rect = sf.ApplyRect(rect)
+
+	return e, true
+}
+
+// a little slack allows for boxes snapped to the edge, or to each other
+const lintTolerance = 1e-6
+
+func rectInside(r, page geo.Rect) bool {
+	return r.Corner.X >= page.Corner.X-lintTolerance &&
+		r.Corner.Y >= page.Corner.Y-lintTolerance &&
+		r.Corner.X+r.Dim.Width <= page.Corner.X+page.Dim.Width+lintTolerance &&
+		r.Corner.Y+r.Dim.Height <= page.Corner.Y+page.Dim.Height+lintTolerance
+}
+
+func rectsOverlap(a, b geo.Rect) bool {
+	return a.Corner.X < b.Corner.X+b.Dim.Width-lintTolerance &&
+		b.Corner.X < a.Corner.X+a.Dim.Width-lintTolerance &&
+		a.Corner.Y < b.Corner.Y+b.Dim.Height-lintTolerance &&
+		b.Corner.Y < a.Corner.Y+a.Dim.Height-lintTolerance
+}
@@ -0,0 +1,154 @@
+package parsesvg
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+var lintLadderSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="textfields"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <rect id="rect1" x="10" y="10" width="20" height="10">
+      <title>mark</title>
+    </rect>
+    <rect id="rect2" x="50" y="10" width="20" height="10">
+      <title>mark</title>
+    </rect>
+    <rect id="rect3" x="10" y="40" width="20" height="10" />
+    <rect id="rect4" x="90" y="90" width="20" height="20">
+      <title>offpage</title>
+    </rect>
+  </g>
+  <g
+     inkscape:label="checkboxes"
+     inkscape:groupmode="layer"
+     id="layer2">
+    <rect id="rect5" x="25" y="15" width="10" height="10">
+      <title>seen</title>
+    </rect>
+  </g>
+  <g
+     inkscape:label="textprefills"
+     inkscape:groupmode="layer"
+     id="layer3">
+    <rect id="rect6" x="10" y="70" width="20" height="10">
+      <desc>{"text":"no size"}</desc>
+      <title>note</title>
+    </rect>
+    <rect id="rect7" x="50" y="70" width="20" height="10">
+      <desc>{"text":"pink","textSize":10,"colorHex":"pink"}</desc>
+      <title>pink</title>
+    </rect>
+  </g>
+`)
+
+var lintLayoutSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="anchors"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <path id="path1" sodipodi:type="arc" sodipodi:cx="0" sodipodi:cy="0" sodipodi:rx="1" sodipodi:ry="1" d="m 0,0">
+      <title>ref-anchor</title>
+    </path>
+    <path id="path2" sodipodi:type="arc" sodipodi:cx="0" sodipodi:cy="0" sodipodi:rx="1" sodipodi:ry="1" d="m 0,0">
+      <title>ref-anchor</title>
+    </path>
+    <path id="path3" sodipodi:type="arc" sodipodi:cx="10" sodipodi:cy="10" sodipodi:rx="1" sodipodi:ry="1" d="m 0,0">
+      <desc>./test/header</desc>
+      <title>header</title>
+    </path>
+    <path id="path4" sodipodi:type="arc" sodipodi:cx="50" sodipodi:cy="10" sodipodi:rx="1" sodipodi:ry="1" d="m 0,0">
+      <desc>./test/footer</desc>
+      <title>footer</title>
+    </path>
+    <path id="path5" sodipodi:type="arc" sodipodi:cx="50" sodipodi:cy="50" sodipodi:rx="1" sodipodi:ry="1" d="m 0,0">
+      <desc>./test/ladder</desc>
+      <title>svg-ladder</title>
+    </path>
+  </g>
+  <g
+     inkscape:label="images"
+     inkscape:groupmode="layer"
+     id="layer2">
+    <rect id="rect1" x="10" y="10" width="20" height="10">
+      <title>image-static-header</title>
+    </rect>
+  </g>
+`)
+
+type lintResult struct {
+	Rule string
+	ID   string
+}
+
+func lintResults(findings []Finding) []lintResult {
+	var results []lintResult
+	for _, f := range findings {
+		results = append(results, lintResult{Rule: f.Rule, ID: f.ID})
+	}
+	return results
+}
+
+func TestValidateLadder(t *testing.T) {
+
+	var svg Csvg__svg
+	err := xml.Unmarshal([]byte(lintLadderSVG), &svg)
+	if err != nil {
+		t.Fatalf("Error parsing svg %v", err)
+	}
+
+	want := []lintResult{
+		{RuleReferenceAnchor, ""},
+		{RuleDuplicateTitle, "rect2"},
+		{RuleUntitled, "rect3"},
+		{RuleTextSize, "rect6"},
+		{RuleTextStyle, "rect7"},
+		{RuleOutsidePage, "rect4"},
+		{RuleOverlap, "rect1"},
+	}
+
+	got := lintResults(ValidateLadder(&svg))
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Ladder findings wrong\n%v\n%v", want, got)
+	}
+
+	var clean Csvg__svg
+	err = xml.Unmarshal([]byte(transformedLadderSVG), &clean)
+	if err != nil {
+		t.Fatalf("Error parsing svg %v", err)
+	}
+
+	if findings := ValidateLadder(&clean); len(findings) != 0 {
+		t.Errorf("Expected no findings, got %v", findings)
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+
+	var svg Csvg__svg
+	err := xml.Unmarshal([]byte(lintLayoutSVG), &svg)
+	if err != nil {
+		t.Fatalf("Error parsing svg %v", err)
+	}
+
+	want := []lintResult{
+		{RuleReferenceAnchor, "path2"},
+		{RuleAnchorWithoutBox, "path4"},
+	}
+
+	findings := ValidateLayout(&svg)
+
+	if !reflect.DeepEqual(lintResults(findings), want) {
+		t.Errorf("Layout findings wrong\n%v\n%v", want, findings)
+	}
+
+	for _, f := range findings {
+		if f.Severity != SeverityError {
+			t.Errorf("Expected an error, got %v", f)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/timdrysdale/geo"
+	"github.com/timdrysdale/unipdf/v3/creator"
+)
+
+// paragraphAlignments are how a prefill can be aligned in its rect
+var paragraphAlignments = map[string]creator.TextAlignment{
+	"":        creator.TextAlignmentLeft,
+	"left":    creator.TextAlignmentLeft,
+	"center":  creator.TextAlignmentCenter,
+	"right":   creator.TextAlignmentRight,
+	"justify": creator.TextAlignmentJustify,
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// check finds anything in the paragraph that can't be drawn as asked, so
+// it can be reported, rather than quietly drawn some other way
+func (p Paragraph) check() error {
+
+	if p.TextFont != "" && !knownFont(p.TextFont) {
+		return errors.New(fmt.Sprintf("textFont %s is not one of the 14 standard fonts, or registered", p.TextFont))
+	}
+
+	if _, ok := paragraphAlignments[p.Alignment]; !ok {
+		return errors.New(fmt.Sprintf("alignment %s should be left, center, right or justify", p.Alignment))
+	}
+
+	if p.ColorHex != "" && !hexColor.MatchString(p.ColorHex) {
+		return errors.New(fmt.Sprintf("colorHex %s should be like #ff0000", p.ColorHex))
+	}
+
+	if len(p.Margins) != 0 && len(p.Margins) != 1 && len(p.Margins) != 4 {
+		return errors.New(fmt.Sprintf("margins should be one number, or four (left, right, top, bottom), not %d", len(p.Margins)))
+	}
+
+	if p.WrapWidth < 0 || p.LineHeight < 0 {
+		return errors.New("wrapWidth and lineHeight can't be negative")
+	}
+
+	return nil
+}
+
+// margins are left, right, top and bottom, as for the creator
+func (p Paragraph) margins() (float64, float64, float64, float64) {
+
+	switch len(p.Margins) {
+	case 1:
+		return p.Margins[0], p.Margins[0], p.Margins[0], p.Margins[0]
+	case 4:
+		return p.Margins[0], p.Margins[1], p.Margins[2], p.Margins[3]
+	}
+
+	return 0, 0, 0, 0
+}
+
+// wrap is whether the text is wrapped. Prefills always wrapped before
+// enableWrap was read, so only an explicit false turns it off.
+func (p Paragraph) wrap() bool {
+	return p.EnableWrap == nil || *p.EnableWrap
+}
+
+// place works out where the paragraph is drawn, and how wide it is, from
+// the prefill's rect, inside its margins. Absolute positioning puts it at
+// xpos, ypos on the page instead, which are otherwise ignored, because
+// older designs have them set to values that were never used.
+func (p Paragraph) place(rect geo.Rect) (geo.Point, float64) {
+
+	left, right, top, _ := p.margins()
+
+	corner := rect.Corner
+
+	if p.AbsolutePositioning {
+		corner = geo.Point{X: p.XPos, Y: p.YPos}
+	}
+
+	width := rect.Dim.Width - left - right
+
+	if p.WrapWidth > 0 {
+		width = p.WrapWidth
+	}
+
+	return geo.Point{X: corner.X + left, Y: corner.Y + top}, width
+}
+
+// draw the prefill with every property of its paragraph
+func (tp TextPrefill) draw(c *creator.Creator, fonts *pageFonts) error {
+
+	text := tp.Text
+
+	err := text.check()
+	if err != nil {
+		return errors.New(fmt.Sprintf("textprefill %s: %v", tp.ID, err))
+	}
+
+	p := c.NewParagraph(text.Text)
+
+	if text.TextFont != "" {
+		font, err := fonts.font(text.TextFont)
+		if err != nil {
+			return err
+		}
+		p.SetFont(font)
+	}
+
+	p.SetFontSize(text.TextSize)
+
+	if text.LineHeight > 0 {
+		p.SetLineHeight(text.LineHeight)
+	}
+
+	if text.ColorHex != "" {
+		p.SetColor(creator.ColorRGBFromHex(text.ColorHex))
+	}
+
+	corner, width := text.place(tp.Rect)
+
+	// alignment needs the width even when the text isn't wrapped, and
+	// wrapping must be set first, because setting the width does the wrap
+	p.SetEnableWrap(text.wrap())
+	if width > 0 {
+		p.SetWidth(width)
+	}
+	p.SetTextAlignment(paragraphAlignments[text.Alignment])
+
+	p.SetAngle(text.Angle)
+
+	p.SetPos(corner.X, corner.Y)
+
+	return c.Draw(p)
+}
@@ -0,0 +1,103 @@
+package parsesvg
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestParagraphCheck(t *testing.T) {
+
+	wrap := true
+
+	good := []Paragraph{
+		Paragraph{},
+		Paragraph{TextFont: "Times-Bold", Alignment: "justify", ColorHex: "#ffee33", Margins: []float64{1, 2, 3, 4}},
+		Paragraph{ColorHex: "#fe3", Margins: []float64{2}, EnableWrap: &wrap, WrapWidth: 50},
+	}
+
+	for _, p := range good {
+		if err := p.check(); err != nil {
+			t.Errorf("%v: unexpected error %v", p, err)
+		}
+	}
+
+	bad := []Paragraph{
+		Paragraph{TextFont: "Comic Sans"},
+		Paragraph{Alignment: "middle"},
+		Paragraph{ColorHex: "ffee33"},
+		Paragraph{Margins: []float64{1, 2}},
+		Paragraph{WrapWidth: -1},
+	}
+
+	for _, p := range bad {
+		if err := p.check(); err == nil {
+			t.Errorf("%v: expected an error", p)
+		}
+	}
+}
+
+func TestParagraphPlace(t *testing.T) {
+
+	rect := geo.Rect{Corner: geo.Point{X: 10, Y: 20}, Dim: geo.Dim{Width: 100, Height: 30}}
+
+	tests := []struct {
+		p      Paragraph
+		corner geo.Point
+		width  float64
+	}{
+		{Paragraph{XPos: 50, YPos: 50}, geo.Point{X: 10, Y: 20}, 100},
+		{Paragraph{Margins: []float64{5, 15, 2, 0}}, geo.Point{X: 15, Y: 22}, 80},
+		{Paragraph{Margins: []float64{5}, WrapWidth: 40}, geo.Point{X: 15, Y: 25}, 40},
+		{Paragraph{AbsolutePositioning: true, XPos: 50, YPos: 60}, geo.Point{X: 50, Y: 60}, 100},
+	}
+
+	for _, test := range tests {
+		corner, width := test.p.place(rect)
+		if corner != test.corner || width != test.width {
+			t.Errorf("%v: got %v %v, expected %v %v", test.p, corner, width, test.corner, test.width)
+		}
+	}
+}
+
+func TestParagraphWrap(t *testing.T) {
+
+	// written before enableWrap was read, when every prefill wrapped
+	svgBytes, err := ioutil.ReadFile("./test/prefill-square.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ladder, err := DefineLadderFromSVG(svgBytes)
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	if len(ladder.TextPrefills) == 0 {
+		t.Fatal("Expected prefills in the ladder")
+	}
+
+	for _, tp := range ladder.TextPrefills {
+		if !tp.Text.wrap() {
+			t.Errorf("Prefill %s from an older design should wrap", tp.ID)
+		}
+	}
+
+	tests := map[string]bool{
+		`{"text":"long"}`:                    true,
+		`{"text":"long","enableWrap":true}`:  true,
+		`{"text":"long","enableWrap":false}`: false,
+	}
+
+	for properties, want := range tests {
+		tp := TextPrefill{Properties: properties}
+		err := UnmarshalTextPrefill(&tp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tp.Text.wrap() != want {
+			t.Errorf("%s: expected wrap %v", properties, want)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package parsesvg
 
 import (
 	"encoding/json"
-	"encoding/xml"
+	"errors"
 	"fmt"
-	"strconv"
+	"io"
 	"strings"
 	"time"
 
@@ -12,19 +12,29 @@ import (
 	"github.com/timdrysdale/geo"
 )
 
+// DefineLayoutFromReader is DefineLayoutFromSVG for an svg that is not
+// already in memory, e.g. from an embed.FS or a zip file
+func DefineLayoutFromReader(r io.Reader) (*Layout, error) {
+
+	input, err := readSvg(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return DefineLayoutFromSVG(input)
+}
+
 func DefineLayoutFromSVG(input []byte) (*Layout, error) {
 
-	var svg Csvg__svg
 	layout := &Layout{}
 
-	err := xml.Unmarshal(input, &svg)
-
+	svg, err := ParseSvgWithOptions(input, DefaultParseOptions())
 	if err != nil {
 		return nil, err
 	}
 
 	// get title
-	if svg.Cmetadata__svg.CRDF__rdf != nil {
+	if svg.Cmetadata__svg != nil && svg.Cmetadata__svg.CRDF__rdf != nil {
 		if svg.Cmetadata__svg.CRDF__rdf.CWork__cc != nil {
 			if svg.Cmetadata__svg.CRDF__rdf.CWork__cc.Ctitle__dc != nil {
 				layout.ID = svg.Cmetadata__svg.CRDF__rdf.CWork__cc.Ctitle__dc.String
@@ -34,90 +44,84 @@ func DefineLayoutFromSVG(input []byte) (*Layout, error) {
 
 	layout.Anchor = geo.Point{X: 0, Y: 0}
 
-	layoutDim, err := getLadderDim(&svg)
+	layoutDim, err := getLadderDim(svg)
 	if err != nil {
 		return nil, err
 	}
 
 	layout.Dim = layoutDim
 
-	var dx, dy float64
+	groups, err := getLayerGroups(svg)
+	if err != nil {
+		return nil, err
+	}
 
 	// look for reference & header/ladder anchor positions
 	// these also contain the base filename in the description
-	for _, g := range svg.Cg__svg {
-		// get transform applied to layer, if any
-		if g.AttrInkscapeSpacelabel == geo.AnchorsLayer {
-			dx, dy = getTranslate(g.Transform)
-
-			layout.Anchors = make(map[string]geo.Point)
-			layout.Filenames = make(map[string]string)
-
+	layout.Anchors = make(map[string]geo.Point)
+	layout.Filenames = make(map[string]string)
+	for _, g := range groups {
+		if g.Layer == geo.AnchorsLayer {
 			for _, r := range g.Cpath__svg {
-				x, err := strconv.ParseFloat(r.Cx, 64)
-				if err != nil {
-					return nil, err
-				}
-				y, err := strconv.ParseFloat(r.Cy, 64)
+
+				anchor, err := getAnchorPoint(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.ID, r.Title)
 				}
 
-				ddx, ddy := getTranslate(r.Transform)
-
-				newX := x + dx + ddx
-				newY := y + dy + ddy
-
 				if r.Title != nil {
 					if r.Title.String == geo.AnchorReference {
 
-						layout.Anchor = geo.Point{X: newX, Y: newY}
+						layout.Anchor = anchor
 					} else {
 
-						layout.Anchors[r.Title.String] = geo.Point{X: newX, Y: newY}
+						layout.Anchors[r.Title.String] = anchor
 
 						if r.Desc != nil {
 							layout.Filenames[r.Title.String] = r.Desc.String
 						}
 					}
 				} else {
-					log.Errorf("Anchor at (%f,%f) has no title, so ignoring\n", newX, newY)
+					log.Errorf("Anchor at (%f,%f) has no title, so ignoring\n", anchor.X, anchor.Y)
 				}
 			}
 		}
 	}
 
-	// look for pageDims
+	// look for pageDims, first from the pages of a multi-page document,
+	// then from rects on the pages layer, which win if both are used
 	layout.PageDims = make(map[string]geo.Dim)
-	for _, g := range svg.Cg__svg {
-		if g.AttrInkscapeSpacelabel == geo.PagesLayer {
+
+	pages, err := getPages(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range pages {
+		name, isDynamic := pageName(p.Label)
+		if name != "" { //reject anonymous pages
+			layout.PageDims[name] = geo.Dim{Width: p.Rect.Dim.Width, Height: p.Rect.Dim.Height, DynamicWidth: isDynamic}
+		} else if len(pages) > 1 {
+			// its anchors would be localised to a page that can't be rendered
+			return nil, elementError(errors.New("page has no label, so it can't be matched to a spread"), "namedview", p.ID, nil)
+		} else {
+			log.Errorf("Page %s has no label, so ignoring\n", p.ID)
+		}
+	}
+
+	for _, g := range groups {
+		if g.Layer == geo.PagesLayer {
 			for _, r := range g.Crect__svg {
-				w, err := strconv.ParseFloat(r.Width, 64)
-				if err != nil {
-					return nil, err
-				}
-				h, err := strconv.ParseFloat(r.Height, 64)
+				rect, err := getRect(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
+				w := rect.Dim.Width
+				h := rect.Dim.Height
 
 				if r.Title != nil { //avoid seg fault, obvs
 
-					fullname := r.Title.String
-					name := ""
-					isDynamic := false
-
-					switch {
-					case strings.HasPrefix(fullname, "page-dynamic-"):
-						name = strings.TrimPrefix(fullname, "page-dynamic-")
-						isDynamic = true
-					case strings.HasPrefix(fullname, "page-static-"):
-						name = strings.TrimPrefix(fullname, "page-static-")
-					default:
-						// unadorned pages are considered static
-						// because this is the least surprising behaviour
-						name = strings.TrimPrefix(fullname, "page-")
-					}
+					name, isDynamic := pageName(r.Title.String)
 
 					if name != "" { //reject anonymous pages
 						layout.PageDims[name] = geo.Dim{Width: w, Height: h, DynamicWidth: isDynamic}
@@ -129,39 +133,36 @@ func DefineLayoutFromSVG(input []byte) (*Layout, error) {
 			}
 		}
 	}
-	// look for previousImageDims
+	// look for previousImageDims, and images placed directly in the layout
 	layout.ImageDims = make(map[string]geo.Dim)
-	for _, g := range svg.Cg__svg {
-		if g.AttrInkscapeSpacelabel == geo.ImagesLayer {
-			for _, r := range g.Crect__svg {
-				w, err := strconv.ParseFloat(r.Width, 64)
+	layout.Images = make(map[string]ImageInsert)
+	for _, g := range groups {
+		if g.Layer == geo.ImagesLayer {
+			for _, im := range g.Cimage__svg {
+				image, err := getImageInsert(im, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, im.Id, im.Title)
+				}
+
+				if im.Title != nil { //avoid seg fault, obvs
+					layout.Images[im.Title.String] = image
+				} else {
+					log.Errorf("Image at (%f,%f) has no title, so ignoring\n", image.Corner.X, image.Corner.Y)
 				}
-				h, err := strconv.ParseFloat(r.Height, 64)
+			}
+			for _, r := range g.Crect__svg {
+				rect, err := getRect(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
+				w := rect.Dim.Width
+				h := rect.Dim.Height
 
 				if r.Title != nil { //avoid seg fault, obvs
 
 					fullname := r.Title.String
-					name := ""
-					isDynamic := false
-
-					switch {
-					case strings.HasPrefix(fullname, "image-dynamic-"):
-						name = strings.TrimPrefix(fullname, "image-dynamic-")
-						name = strings.TrimPrefix(name, "width-")  //we may want this later, so leave in API
-						name = strings.TrimPrefix(name, "height-") //getting info from box size for now
-						isDynamic = true
-					case strings.HasPrefix(fullname, "image-static-"):
-						name = strings.TrimPrefix(fullname, "image-static-")
-					default:
-						// we're just trying to strip off prefixes,
-						// not prevent underadorned names from working
-						name = strings.TrimPrefix(fullname, "image-")
-					}
+					name := imageName(fullname)
+					isDynamic := strings.HasPrefix(fullname, "image-dynamic-")
 
 					if name != "" { //reject anonymous images - can't place them
 						layout.ImageDims[name] = geo.Dim{Width: w, Height: h, DynamicWidth: isDynamic}
@@ -174,62 +175,193 @@ func DefineLayoutFromSVG(input []byte) (*Layout, error) {
 		}
 	}
 
-	err = ApplyDocumentUnitsScaleLayout(&svg, layout)
+	err = ApplyDocumentUnitsScaleLayout(svg, layout)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(pages) > 1 {
+		err = localiseAnchors(svg, layout, pages)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return layout, nil
 }
 
+// inkscapePage is a page of a multi-page document, in user units
+type inkscapePage struct {
+	ID    string
+	Label string
+	Rect  geo.Rect
+}
+
+func getPages(svg *Csvg__svg) ([]inkscapePage, error) {
+
+	var pages []inkscapePage
+
+	if svg.Cnamedview__sodipodi == nil { //avoid seg fault, obvs
+		return pages, nil
+	}
+
+	for _, p := range svg.Cnamedview__sodipodi.Cpage__inkscape {
+
+		x, err := parseUserUnits(p.Id, "x", p.X, true)
+		if err != nil {
+			return nil, elementError(err, "namedview", p.Id, nil)
+		}
+		y, err := parseUserUnits(p.Id, "y", p.Y, true)
+		if err != nil {
+			return nil, elementError(err, "namedview", p.Id, nil)
+		}
+		w, err := parseUserUnits(p.Id, "width", p.Width, false)
+		if err != nil {
+			return nil, elementError(err, "namedview", p.Id, nil)
+		}
+		h, err := parseUserUnits(p.Id, "height", p.Height, false)
+		if err != nil {
+			return nil, elementError(err, "namedview", p.Id, nil)
+		}
+
+		pages = append(pages, inkscapePage{
+			ID:    p.Id,
+			Label: p.AttrInkscapeSpacelabel,
+			Rect:  geo.Rect{Corner: geo.Point{X: x, Y: y}, Dim: geo.Dim{Width: w, Height: h}},
+		})
+	}
+
+	return pages, nil
+}
+
+// localiseAnchors moves each anchor (and image) to be relative to the top left corner
+// of the page it is drawn on, so that every page of a multi-page document
+// can be laid out as if it were the only one. Anchors that are not on any
+// page are left where they are. Only positions are moved: the ImageDims
+// are just the size of the box for the previous image, which is placed at
+// its anchor, and page dims are sizes already.
+func localiseAnchors(svg *Csvg__svg, layout *Layout, pages []inkscapePage) error {
+
+	sf, err := getUserUnitTransform(svg)
+	if err != nil {
+		return err
+	}
+
+	var corners []geo.Rect
+	for _, p := range pages {
+		corners = append(corners, sf.ApplyRect(p.Rect))
+	}
+
+	localise := func(point geo.Point) geo.Point {
+		for _, page := range corners {
+			if onPage(point, page) {
+				return DiffPosition(page.Corner, point)
+			}
+		}
+		return point
+	}
+
+	layout.Anchor = localise(layout.Anchor)
+
+	for k, v := range layout.Anchors {
+		layout.Anchors[k] = localise(v)
+	}
+
+	for k, v := range layout.Images {
+		v.Corner = localise(v.Corner)
+		layout.Images[k] = v
+	}
+
+	return nil
+}
+
+// onPage includes the top and left edges, but not the bottom and right,
+// so that a point on the boundary between two pages belongs to the one
+// it is the corner of
+func onPage(point geo.Point, page geo.Rect) bool {
+	return point.X >= page.Corner.X &&
+		point.Y >= page.Corner.Y &&
+		point.X < page.Corner.X+page.Dim.Width &&
+		point.Y < page.Corner.Y+page.Dim.Height
+}
+
+// pageName strips the prefixes from a page title or label, to give the
+// name of the spread, and whether its width is dynamic
+func pageName(title string) (string, bool) {
+
+	switch {
+	case strings.HasPrefix(title, "page-dynamic-"):
+		return strings.TrimPrefix(title, "page-dynamic-"), true
+	case strings.HasPrefix(title, "page-static-"):
+		return strings.TrimPrefix(title, "page-static-"), false
+	}
+
+	// unadorned pages are considered static
+	// because this is the least surprising behaviour
+	return strings.TrimPrefix(title, "page-"), false
+}
+
 func ApplyDocumentUnitsScaleLayout(svg *Csvg__svg, layout *Layout) error {
 
 	// iterate through the structure applying the conversion from
-	// document units to points
+	// user units to points
 
 	//note we do NOT apply the modification to ladder.DIM because this has its own
 	//units in it and has already been handled.
 
-	units := svg.Cnamedview__sodipodi.AttrInkscapeSpacedocument_dash_units
-
-	sf := float64(1)
-
-	switch units {
-	case "mm":
-		sf = geo.PPMM
-	case "px":
-		sf = geo.PPPX
-	case "pt":
-		sf = 1
-	case "in":
-		sf = geo.PPIN
+	sf, err := getUserUnitTransform(svg)
+	if err != nil {
+		return err
 	}
 
-	layout.Anchor.X = sf * layout.Anchor.X
-	layout.Anchor.Y = sf * layout.Anchor.Y
+	layout.Anchor = sf.Apply(layout.Anchor)
 
 	for k, v := range layout.Anchors {
-		v.X = sf * v.X
-		v.Y = sf * v.Y
-		layout.Anchors[k] = v
-
+		layout.Anchors[k] = sf.Apply(v)
 	}
+
+	// dims are lengths, not positions, so only scale them
 	for k, v := range layout.PageDims {
-		v.Width = sf * v.Width
-		v.Height = sf * v.Height
+		v.Width = sf[0] * v.Width
+		v.Height = sf[3] * v.Height
 		layout.PageDims[k] = v
 
 	}
 
 	for k, v := range layout.ImageDims {
-		v.Width = sf * v.Width
-		v.Height = sf * v.Height
+		v.Width = sf[0] * v.Width
+		v.Height = sf[3] * v.Height
 		layout.ImageDims[k] = v
 	}
 
+	for k, v := range layout.Images {
+		rect := sf.ApplyRect(geo.Rect{Corner: v.Corner, Dim: v.Dim})
+		v.Corner = rect.Corner
+		v.Dim = rect.Dim
+		layout.Images[k] = v
+	}
+
 	return nil
 }
 
+// imageName strips the prefixes from an image box title, to give the name
+// of the anchor it goes with
+func imageName(title string) string {
+
+	switch {
+	case strings.HasPrefix(title, "image-dynamic-"):
+		name := strings.TrimPrefix(title, "image-dynamic-")
+		name = strings.TrimPrefix(name, "width-")  //we may want this later, so leave in API
+		return strings.TrimPrefix(name, "height-") //getting info from box size for now
+	case strings.HasPrefix(title, "image-static-"):
+		return strings.TrimPrefix(title, "image-static-")
+	}
+
+	// we're just trying to strip off prefixes,
+	// not prevent underadorned names from working
+	return strings.TrimPrefix(title, "image-")
+}
+
 func PrettyPrintLayout(layout *Layout) error {
 
 	json, err := json.MarshalIndent(layout, "", "\t")
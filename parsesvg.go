@@ -2,9 +2,9 @@ package parsesvg
 
 import (
 	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"regexp"
 	"sort"
 	"strconv"
@@ -14,75 +14,269 @@ import (
 	"github.com/timdrysdale/geo"
 )
 
-func ParseSvg(input []byte) *Csvg__svg {
+// layerGroup is a <g> found at any depth in the document, along with the
+// label of the layer it belongs to, and its transform composed with those
+// of all its ancestors
+type layerGroup struct {
+	*Cg__svg
+	Layer string
+	CTM   Matrix
+}
+
+// getLayerGroups walks the tree of layers, sublayers and groups, so that
+// elements can be found no matter how deeply the designer has nested them.
+// A group belongs to the nearest enclosing inkscape layer (or sublayer);
+// top level groups are treated as layers whatever their groupmode, as
+// they always have been. Groups are returned in document order.
+func getLayerGroups(svg *Csvg__svg) ([]layerGroup, error) {
 
-	var svg Csvg__svg
+	var groups []layerGroup
 
-	xml.Unmarshal(input, &svg)
+	for _, g := range svg.Cg__svg {
+		err := walkGroup(g, g.AttrInkscapeSpacelabel, Identity, &groups)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return &svg
+	return groups, nil
 }
 
-// return the translation applied to the SVG object
-// does not mangle the Y dimension
-func getTranslate(transform string) (float64, float64) {
+func walkGroup(g *Cg__svg, layer string, parent Matrix, groups *[]layerGroup) error {
 
-	if len(transform) <= 0 {
-		return 0.0, 0.0
+	own, err := ParseTransform(g.Transform)
+	if err != nil {
+		return &ElementError{Layer: layer, ID: g.Attrid, Attribute: "transform", Value: g.Transform, Err: err}
 	}
 
-	if !strings.Contains(transform, geo.Translate) {
-		return 0.0, 0.0
+	ctm := parent.Multiply(own)
+
+	*groups = append(*groups, layerGroup{Cg__svg: g, Layer: layer, CTM: ctm})
+
+	for _, child := range g.Cg__svg {
+
+		childLayer := layer
+
+		if child.AttrInkscapeSpacegroupmode == "layer" {
+			childLayer = child.AttrInkscapeSpacelabel
+		}
+
+		err := walkGroup(child, childLayer, ctm, groups)
+		if err != nil {
+			return err
+		}
 	}
 
-	openBracket := strings.Index(transform, "(")
-	comma := strings.Index(transform, ",")
-	closeBracket := strings.Index(transform, ")")
+	return nil
+}
+
+// getRect returns the position and size of a rect once its own transform,
+// and the transform it inherits (ctm), are applied
+func getRect(r *Crect__svg, ctm Matrix) (geo.Rect, error) {
+
+	rect := geo.Rect{}
 
-	if openBracket < 0 || comma < 0 || closeBracket < 0 {
-		return 0.0, 0.0
+	w, err := parseUserUnits(r.Id, "width", r.Width, false)
+	if err != nil {
+		return rect, err
+	}
+	h, err := parseUserUnits(r.Id, "height", r.Height, false)
+	if err != nil {
+		return rect, err
+	}
+	x, err := parseUserUnits(r.Id, "x", r.Rx, true)
+	if err != nil {
+		return rect, err
+	}
+	y, err := parseUserUnits(r.Id, "y", r.Ry, true)
+	if err != nil {
+		return rect, err
 	}
 
-	if openBracket == comma || comma == closeBracket {
-		return 0.0, 0.0
+	own, err := ParseTransform(r.Transform)
+	if err != nil {
+		return rect, attributeError(r.Id, "transform", r.Transform, err)
 	}
 
-	dx, err := strconv.ParseFloat(transform[openBracket+1:comma], 64)
+	rect.Corner = geo.Point{X: x, Y: y}
+	rect.Dim = geo.Dim{Width: w, Height: h, DynamicWidth: false}
+
+	return ctm.Multiply(own).ApplyRect(rect), nil
+}
+
+// getCircleRect returns the box around a circle once its own transform,
+// and the transform it inherits (ctm), are applied
+func getCircleRect(c *Ccircle__svg, ctm Matrix) (geo.Rect, error) {
+
+	rect := geo.Rect{}
+
+	x, err := parseUserUnits(c.Id, "cx", c.Cx, true)
 	if err != nil {
-		return 0.0, 0.0
+		return rect, err
 	}
-	dy, err := strconv.ParseFloat(transform[comma+1:closeBracket], 64)
+	y, err := parseUserUnits(c.Id, "cy", c.Cy, true)
 	if err != nil {
-		return 0.0, 0.0
+		return rect, err
+	}
+	r, err := parseUserUnits(c.Id, "r", c.R, false)
+	if err != nil {
+		return rect, err
+	}
+
+	own, err := ParseTransform(c.Transform)
+	if err != nil {
+		return rect, attributeError(c.Id, "transform", c.Transform, err)
 	}
 
-	return dx, dy
+	rect.Corner = geo.Point{X: x - r, Y: y - r}
+	rect.Dim = geo.Dim{Width: 2 * r, Height: 2 * r, DynamicWidth: false}
 
+	return ctm.Multiply(own).ApplyRect(rect), nil
+}
+
+// getArcRect returns the box around a circle or ellipse that inkscape has
+// saved as a path, once its own transform, and the transform it inherits
+// (ctm), are applied
+func getArcRect(p *Cpath__svg, ctm Matrix) (geo.Rect, error) {
+
+	rect := geo.Rect{}
+
+	x, err := parseUserUnits(p.ID, "sodipodi:cx", p.Cx, true)
+	if err != nil {
+		return rect, err
+	}
+	y, err := parseUserUnits(p.ID, "sodipodi:cy", p.Cy, true)
+	if err != nil {
+		return rect, err
+	}
+	rx, err := parseUserUnits(p.ID, "sodipodi:rx", p.AttrSodipodiSpacerx, false)
+	if err != nil {
+		return rect, err
+	}
+	ry, err := parseUserUnits(p.ID, "sodipodi:ry", p.AttrSodipodiSpacery, false)
+	if err != nil {
+		return rect, err
+	}
+
+	own, err := ParseTransform(p.Transform)
+	if err != nil {
+		return rect, attributeError(p.ID, "transform", p.Transform, err)
+	}
+
+	rect.Corner = geo.Point{X: x - rx, Y: y - ry}
+	rect.Dim = geo.Dim{Width: 2 * rx, Height: 2 * ry, DynamicWidth: false}
+
+	return ctm.Multiply(own).ApplyRect(rect), nil
+}
+
+// getAnchorPoint returns the centre of a circular anchor once its own
+// transform, and the transform it inherits (ctm), are applied
+func getAnchorPoint(r *Cpath__svg, ctm Matrix) (geo.Point, error) {
+
+	// an anchor that isn't a circle has no centre, so don't default it
+	x, err := parseUserUnits(r.ID, "sodipodi:cx", r.Cx, false)
+	if err != nil {
+		return geo.Point{}, err
+	}
+	y, err := parseUserUnits(r.ID, "sodipodi:cy", r.Cy, false)
+	if err != nil {
+		return geo.Point{}, err
+	}
+
+	own, err := ParseTransform(r.Transform)
+	if err != nil {
+		return geo.Point{}, attributeError(r.ID, "transform", r.Transform, err)
+	}
+
+	return ctm.Multiply(own).Apply(geo.Point{X: x, Y: y}), nil
 }
 
 func scanUnitStringToPP(str string) (float64, error) {
 
-	str = strings.TrimSpace(str)
-	length := len(str)
-	units := str[length-2 : length]
-	value, err := strconv.ParseFloat(str[0:length-2], 64)
+	value, pp, err := scanUnitString(str)
+	if err != nil {
+		return 0, err
+	}
+
+	return value * pp, nil
+
+}
+
+// scanUnitString splits a length into its value, and the number of points
+// per unit, so callers can take ratios of values before converting to points
+func scanUnitString(str string) (float64, float64, error) {
+
+	length, err := ParseLength(str)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	pp, err := length.PointsPerUnit()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return length.Value, pp, nil
+
+}
+
+// getUserUnitTransform returns the transform from the user units that
+// elements are drawn in, to points. If there is a viewBox, then it is
+// mapped onto the width and height, which may scale x and y differently,
+// else we take the user units to be the document units.
+func getUserUnitTransform(svg *Csvg__svg) (Matrix, error) {
+
+	if strings.TrimSpace(svg.AttrviewBox) == "" {
+
+		sf := float64(1)
+
+		units := ""
+		if svg.Cnamedview__sodipodi != nil { //avoid seg fault, obvs
+			units = svg.Cnamedview__sodipodi.AttrInkscapeSpacedocument_dash_units
+		}
+
+		switch units {
+		case "mm":
+			sf = geo.PPMM
+		case "px":
+			sf = geo.PPPX
+		case "pt":
+			sf = 1
+		case "in":
+			sf = geo.PPIN
+		}
+
+		return Matrix{sf, 0, 0, sf, 0, 0}, nil
+	}
+
+	box, err := parseTransformArgs(svg.AttrviewBox)
+	if err == nil && len(box) != 4 {
+		err = errors.New("expected four numbers: min-x, min-y, width and height")
+	}
+	if err != nil {
+		return Identity, attributeError(svg.Attrid, "viewBox", svg.AttrviewBox, err)
+	}
+
+	if box[2] <= 0 || box[3] <= 0 {
+		return Identity, attributeError(svg.Attrid, "viewBox", svg.AttrviewBox, errors.New("must have a positive width and height"))
+	}
+
+	w, wpp, err := scanUnitString(svg.Width)
 	if err != nil {
-		return 0, errors.New(fmt.Sprintf("Couldn't parse  %s when split into value %s with units %s", str, str[0:length-2], units))
+		return Identity, attributeError(svg.Attrid, "width", svg.Width, err)
 	}
 
-	switch units {
-	case "mm":
-		return value * geo.PPMM, nil
-	case "px":
-		return value * geo.PPPX, nil
-	case "pt":
-		return value, nil //TODO check pt doesn't somehow default to not present
-	case "in":
-		return value * geo.PPIN, nil
+	h, hpp, err := scanUnitString(svg.Height)
+	if err != nil {
+		return Identity, attributeError(svg.Attrid, "height", svg.Height, err)
 	}
 
-	return 0, errors.New(fmt.Sprintf("didn't understand the units %s", units))
+	// divide before converting to points, so that the usual case of a
+	// viewBox in the same units as the page gives exactly the unit size
+	sx := wpp * (w / box[2])
+	sy := hpp * (h / box[3])
 
+	return Matrix{sx, 0, 0, sy, -box[0] * sx, -box[1] * sy}, nil
 }
 
 func getLadderDim(svg *Csvg__svg) (geo.Dim, error) {
@@ -94,29 +288,39 @@ func getLadderDim(svg *Csvg__svg) (geo.Dim, error) {
 
 	w, err := scanUnitStringToPP(svg.Width)
 	if err != nil {
-		return dim, err
+		return dim, attributeError(svg.Attrid, "width", svg.Width, err)
 	}
 	h, err := scanUnitStringToPP(svg.Height)
 	if err != nil {
-		return dim, err
+		return dim, attributeError(svg.Attrid, "height", svg.Height, err)
 	}
 
 	return geo.Dim{Width: w, Height: h, DynamicWidth: false}, nil
 
 }
 
+// DefineLadderFromReader is DefineLadderFromSVG for an svg that is not
+// already in memory, e.g. from an embed.FS or a zip file
+func DefineLadderFromReader(r io.Reader) (*Ladder, error) {
+
+	input, err := readSvg(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return DefineLadderFromSVG(input)
+}
+
 func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 
-	var svg Csvg__svg
 	ladder := &Ladder{}
 
-	err := xml.Unmarshal(input, &svg)
-
+	svg, err := ParseSvgWithOptions(input, DefaultParseOptions())
 	if err != nil {
 		return nil, err
 	}
 
-	if svg.Cmetadata__svg.CRDF__rdf != nil {
+	if svg.Cmetadata__svg != nil && svg.Cmetadata__svg.CRDF__rdf != nil {
 		if svg.Cmetadata__svg.CRDF__rdf.CWork__cc != nil {
 			if svg.Cmetadata__svg.CRDF__rdf.CWork__cc.Ctitle__dc != nil {
 				ladder.ID = svg.Cmetadata__svg.CRDF__rdf.CWork__cc.Ctitle__dc.String
@@ -127,43 +331,31 @@ func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 	//fmt.Printf("-------(%s)-----------\n", ladder.ID)
 	ladder.Anchor = geo.Point{X: 0, Y: 0}
 
-	ladderDim, err := getLadderDim(&svg)
+	ladderDim, err := getLadderDim(svg)
 	if err != nil {
 		return nil, err
 	}
 
 	ladder.Dim = ladderDim
 
-	var dx, dy float64
+	groups, err := getLayerGroups(svg)
+	if err != nil {
+		return nil, err
+	}
+
 	//fmt.Println("Looking for reference anchor")
 	// look for reference anchor position
-	for _, g := range svg.Cg__svg {
-		// get transform applied to layer, if any
-		//fmt.Println(g.AttrInkscapeSpacelabel)
-		if g.AttrInkscapeSpacelabel == geo.AnchorsLayer {
-			dx, dy = getTranslate(g.Transform)
-			//fmt.Printf("Anchors layer global translates %f,%f\n", dx, dy)
-
-		}
+	for _, g := range groups {
+		//fmt.Println(g.Layer)
 		for _, r := range g.Cpath__svg {
 			if r.Title != nil {
 				if r.Title.String == geo.AnchorReference { // was force true?
-					x, err := strconv.ParseFloat(r.Cx, 64)
+					anchor, err := getAnchorPoint(r, g.CTM)
 					if err != nil {
-						return nil, err
+						return nil, elementError(err, g.Layer, r.ID, r.Title)
 					}
-					y, err := strconv.ParseFloat(r.Cy, 64)
-					if err != nil {
-						return nil, err
-					}
-
-					ddx, ddy := getTranslate(r.Transform)
-					//fmt.Printf("Anchor %s local translates %f,%f\n", r.Title.String, ddx, ddy)
-					newX := x + dx + ddx
-					newY := y + dy + ddy
-					//fmt.Printf("X: %f, Y:%f\n", x, y)
-					//fmt.Printf("tX: %f, tY:%f\n", newX, newY)
-					ladder.Anchor = geo.Point{X: newX, Y: newY}
+					//fmt.Printf("X: %f, Y:%f\n", anchor.X, anchor.Y)
+					ladder.Anchor = anchor
 				}
 			} else {
 				//fmt.Println("un-named path")
@@ -172,8 +364,8 @@ func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 	}
 
 	// look for textFields
-	for _, g := range svg.Cg__svg {
-		if g.AttrInkscapeSpacelabel == geo.TextFieldsLayer {
+	for _, g := range groups {
+		if g.Layer == geo.TextFieldsLayer {
 			for _, r := range g.Crect__svg {
 				tf := TextField{}
 				if r.Title != nil { //avoid seg fault, obvs
@@ -183,44 +375,27 @@ func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 				tf.TabSequence = getTabSequence(r)
 
 				if r.Desc != nil {
-					tf.Prefill = r.Desc.String
-				}
-				w, err := strconv.ParseFloat(r.Width, 64)
-				if err != nil {
-					return nil, err
-				}
-				h, err := strconv.ParseFloat(r.Height, 64)
-				if err != nil {
-					return nil, err
+					err = UnmarshalTextField(&tf, r.Desc.String)
+					if err != nil {
+						return nil, elementError(err, g.Layer, r.Id, r.Title)
+					}
 				}
 
-				tf.Rect.Dim.Width = w
-				tf.Rect.Dim.Height = h
-				tf.Rect.Dim.DynamicWidth = false
-				dx, dy := getTranslate(r.Transform)
-				//fmt.Printf("transform in play %f %f\n", dx, dy)
-				x, err := strconv.ParseFloat(r.Rx, 64)
-				if err != nil {
-					return nil, err
-				}
-				y, err := strconv.ParseFloat(r.Ry, 64)
+				tf.Rect, err = getRect(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
-
-				tf.Rect.Corner.X = x + dx
-				tf.Rect.Corner.Y = y + dy
 				//fmt.Printf("textfield corner at %f %f\n", tf.Rect.Corner.X, tf.Rect.Corner.Y)
 				ladder.TextFields = append(ladder.TextFields, tf)
 			}
 		}
 
 	}
-	
+
 	// look for placeholders for parts and marks
-	for _, g := range svg.Cg__svg {
-	// TODO - Tidy this up so that "placeholders" is treated in a similar way to other layers
-		if g.AttrInkscapeSpacelabel == "placeholders" {
+	for _, g := range groups {
+		// TODO - Tidy this up so that "placeholders" is treated in a similar way to other layers
+		if g.Layer == "placeholders" {
 			for _, r := range g.Crect__svg {
 				tf := TextField{}
 				if r.Title != nil { //avoid seg fault, obvs
@@ -232,31 +407,17 @@ func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 				if r.Desc != nil {
 					tf.Prefill = r.Desc.String
 				}
-				w, err := strconv.ParseFloat(r.Width, 64)
-				if err != nil {
-					return nil, err
-				}
-				h, err := strconv.ParseFloat(r.Height, 64)
-				if err != nil {
-					return nil, err
-				}
 
-				tf.Rect.Dim.Width = w
-				tf.Rect.Dim.Height = h
-				tf.Rect.Dim.DynamicWidth = false
-				dx, dy := getTranslate(r.Transform)
-				//fmt.Printf("transform in play %f %f\n", dx, dy)
-				x, err := strconv.ParseFloat(r.Rx, 64)
+				// check now, so a mistake is found with the element's details
+				_, _, err = UnmarshalPlaceholder(tf)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
-				y, err := strconv.ParseFloat(r.Ry, 64)
+
+				tf.Rect, err = getRect(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
-
-				tf.Rect.Corner.X = x + dx
-				tf.Rect.Corner.Y = y + dy
 				//fmt.Printf("textfield corner at %f %f\n", tf.Rect.Corner.X, tf.Rect.Corner.Y)
 				ladder.Placeholders = append(ladder.Placeholders, tf)
 			}
@@ -271,8 +432,8 @@ func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 
 	// look for prefill textboxes (not editable in pdf)
 
-	for _, g := range svg.Cg__svg {
-		if g.AttrInkscapeSpacelabel == geo.TextPrefillsLayer {
+	for _, g := range groups {
+		if g.Layer == geo.TextPrefillsLayer {
 			for _, r := range g.Crect__svg {
 				tp := TextPrefill{}
 				if r.Title != nil { //avoid seg fault, obvs
@@ -282,42 +443,160 @@ func DefineLadderFromSVG(input []byte) (*Ladder, error) {
 				if r.Desc != nil {
 					tp.Properties = r.Desc.String
 				}
-				w, err := strconv.ParseFloat(r.Width, 64)
+
+				tp.Rect, err = getRect(r, g.CTM) // rotated boxes become their bounding box
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
-				h, err := strconv.ParseFloat(r.Height, 64)
+
+				err = UnmarshalTextPrefill(&tp)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
+				}
+				ladder.TextPrefills = append(ladder.TextPrefills, tp)
+			}
+		}
+
+	}
+
+	// look for checkboxes
+
+	for _, g := range groups {
+		if g.Layer == CheckBoxesLayer {
+			for _, r := range g.Crect__svg {
+				cb := CheckBox{}
+				if r.Title != nil { //avoid seg fault, obvs
+					cb.ID = r.Title.String
+				}
+
+				cb.TabSequence = getTabSequence(r)
+
+				if r.Desc != nil {
+					cb.Properties = r.Desc.String
 				}
 
-				tp.Rect.Dim.Width = w
-				tp.Rect.Dim.Height = h
-				tp.Rect.Dim.DynamicWidth = false
-				dx, dy := getTranslate(r.Transform) //check if rotate will cause box to be out of place
-				x, err := strconv.ParseFloat(r.Rx, 64)
+				cb.Rect, err = getRect(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
-				y, err := strconv.ParseFloat(r.Ry, 64)
+
+				err = UnmarshalCheckBox(&cb)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
 				}
+				ladder.CheckBoxes = append(ladder.CheckBoxes, cb)
+			}
+		}
+	}
 
-				tp.Rect.Corner.X = x + dx
-				tp.Rect.Corner.Y = y + dy
+	sort.Slice(ladder.CheckBoxes, func(i, j int) bool {
+		return ladder.CheckBoxes[i].TabSequence < ladder.CheckBoxes[j].TabSequence
+	})
 
-				err = UnmarshalTextPrefill(&tp)
+	// look for dropdowns and comboboxes, which only differ in whether
+	// the user can type in their own value
+
+	for _, g := range groups {
+		if g.Layer == DropDownsLayer || g.Layer == ComboBoxesLayer {
+			for _, r := range g.Crect__svg {
+				dd := DropDown{}
+				if r.Title != nil { //avoid seg fault, obvs
+					dd.ID = r.Title.String
+				}
+
+				dd.TabSequence = getTabSequence(r)
+
+				if r.Desc != nil {
+					dd.Properties = r.Desc.String
+				}
+
+				dd.Rect, err = getRect(r, g.CTM)
 				if err != nil {
-					return nil, err
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
+				}
+
+				editable := g.Layer == ComboBoxesLayer
+
+				dd.Options, err = UnmarshalChoiceOptions(dd.ID, dd.Properties, editable)
+				if err != nil {
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
+				}
+
+				if editable {
+					ladder.ComboBoxes = append(ladder.ComboBoxes, ComboBox(dd))
+				} else {
+					ladder.DropDowns = append(ladder.DropDowns, dd)
 				}
-				ladder.TextPrefills = append(ladder.TextPrefills, tp)
 			}
 		}
+	}
+
+	sort.Slice(ladder.DropDowns, func(i, j int) bool {
+		return ladder.DropDowns[i].TabSequence < ladder.DropDowns[j].TabSequence
+	})
+
+	sort.Slice(ladder.ComboBoxes, func(i, j int) bool {
+		return ladder.ComboBoxes[i].TabSequence < ladder.ComboBoxes[j].TabSequence
+	})
 
+	// look for radio buttons, which may be drawn as rects or circles
+
+	radios := radioGroups{}
+
+	for _, g := range groups {
+		if g.Layer == RadioButtonsLayer {
+			for _, r := range g.Crect__svg {
+				rect, err := getRect(r, g.CTM)
+				if err != nil {
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
+				}
+				err = radios.add(r.Title, r.Desc, r.Id, rect)
+				if err != nil {
+					return nil, elementError(err, g.Layer, r.Id, r.Title)
+				}
+			}
+			for _, c := range g.Ccircle__svg {
+				rect, err := getCircleRect(c, g.CTM)
+				if err != nil {
+					return nil, elementError(err, g.Layer, c.Id, c.Title)
+				}
+				err = radios.add(c.Title, c.Desc, c.Id, rect)
+				if err != nil {
+					return nil, elementError(err, g.Layer, c.Id, c.Title)
+				}
+			}
+			for _, p := range g.Cpath__svg {
+				if p.AttrSodipodiSpacetype != "arc" {
+					continue
+				}
+				rect, err := getArcRect(p, g.CTM)
+				if err != nil {
+					return nil, elementError(err, g.Layer, p.ID, p.Title)
+				}
+				err = radios.add(p.Title, p.Desc, p.ID, rect)
+				if err != nil {
+					return nil, elementError(err, g.Layer, p.ID, p.Title)
+				}
+			}
+		}
 	}
 
-	err = ApplyDocumentUnits(&svg, ladder)
+	ladder.RadioGroups = radios.sorted()
+
+	// look for images placed directly in the ladder
+	for _, g := range groups {
+		if g.Layer == geo.ImagesLayer {
+			for _, im := range g.Cimage__svg {
+				image, err := getImageInsert(im, g.CTM)
+				if err != nil {
+					return nil, elementError(err, g.Layer, im.Id, im.Title)
+				}
+				ladder.Images = append(ladder.Images, image)
+			}
+		}
+	}
+
+	err = ApplyDocumentUnits(svg, ladder)
 	if err != nil {
 		return nil, err
 	}
@@ -343,31 +622,244 @@ func UnmarshalTextPrefill(tp *TextPrefill) error {
 
 }
 
+// UnmarshalTextField reads the textfield's description. A JSON object is
+// taken as options, anything else is the prefill, as it always was.
+func UnmarshalTextField(tf *TextField, description string) error {
+
+	if !strings.HasPrefix(strings.TrimSpace(description), "{") {
+		tf.Prefill = description
+		return nil
+	}
+
+	var options TextFieldOptions
+
+	err := json.Unmarshal([]byte(description), &options)
+	if err != nil {
+		return errors.New(fmt.Sprintf("textfield %s has a description that looks like JSON but isn't: %v", tf.ID, err))
+	}
+
+	if options.MaxLen < 0 {
+		return errors.New(fmt.Sprintf("textfield %s can't have a negative maxLen", tf.ID))
+	}
+
+	if options.Comb && (options.MaxLen == 0 || options.Multiline) {
+		return errors.New(fmt.Sprintf("textfield %s must have a maxLen, and not be multiline, to be a comb", tf.ID))
+	}
+
+	if options.MaxLen > 0 && len([]rune(options.Prefill)) > options.MaxLen {
+		return errors.New(fmt.Sprintf("textfield %s has a prefill longer than its maxLen", tf.ID))
+	}
+
+	if options.FontSize < 0 {
+		return errors.New(fmt.Sprintf("textfield %s can't have a negative fontSize", tf.ID))
+	}
+
+	if options.Font != "" && !knownFont(options.Font) {
+		return errors.New(fmt.Sprintf("textfield %s has font %s, which is not one of the standard fonts, or registered", tf.ID, options.Font))
+	}
+
+	if _, ok := fieldAlignments[options.Align]; options.Align != "" && !ok {
+		return errors.New(fmt.Sprintf("textfield %s has align %s, which should be left, center or right", tf.ID, options.Align))
+	}
+
+	// declaring any limit makes the field numeric
+	if options.Min != nil || options.Max != nil || options.Step != 0 {
+		options.Numeric = true
+	}
+
+	// a total is worked out by the viewer, so the marker can't edit it
+	if len(options.Sum) > 0 {
+		options.Numeric = true
+		options.ReadOnly = true
+	}
+
+	for _, id := range options.Sum {
+		if id == "" || id == tf.ID {
+			return errors.New(fmt.Sprintf("textfield %s can't sum %q", tf.ID, id))
+		}
+	}
+
+	if options.Step < 0 {
+		return errors.New(fmt.Sprintf("textfield %s can't have a negative step", tf.ID))
+	}
+
+	if options.Min != nil && options.Max != nil && *options.Min > *options.Max {
+		return errors.New(fmt.Sprintf("textfield %s has a min that is bigger than its max", tf.ID))
+	}
+
+	if options.Numeric && options.Prefill != "" {
+		if _, err := strconv.ParseFloat(options.Prefill, 64); err != nil {
+			return errors.New(fmt.Sprintf("textfield %s is numeric, so can't be prefilled with %s", tf.ID, options.Prefill))
+		}
+	}
+
+	tf.Prefill = options.Prefill
+	tf.Options = options
+
+	return nil
+}
+
+// UnmarshalCheckBox reads the checkbox options from its Properties,
+// defaulting to unchecked, with an on value of "Yes"
+func UnmarshalCheckBox(cb *CheckBox) error {
+
+	options := CheckBoxOptions{OnValue: "Yes"}
+
+	if len(strings.TrimSpace(cb.Properties)) > 0 {
+		err := json.Unmarshal([]byte(cb.Properties), &options)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.OnValue == "" || options.OnValue == "Off" {
+		return errors.New(fmt.Sprintf("checkbox %s can't have an on value of %q", cb.ID, options.OnValue))
+	}
+
+	cb.Options = options
+
+	return nil
+}
+
+var radioTitle = regexp.MustCompile(`^(.+)-radio-(.+)$`)
+
+// radioGroups collects radio buttons by group name, remembering the order
+// in which the groups were first seen
+type radioGroups struct {
+	names  []string
+	groups map[string]*RadioGroup
+}
+
+func (rgs *radioGroups) add(title *Ctitle__svg, desc *Cdesc__svg, id string, rect geo.Rect) error {
+
+	if title == nil {
+		return errors.New(fmt.Sprintf("radio button %s has no title, so can't tell which group it is in", id))
+	}
+
+	match := radioTitle.FindStringSubmatch(title.String)
+	if match == nil {
+		return errors.New(fmt.Sprintf("radio button %s must be titled <group>-radio-<value>", title.String))
+	}
+
+	if rgs.groups == nil {
+		rgs.groups = make(map[string]*RadioGroup)
+	}
+
+	name := match[1]
+
+	rg, ok := rgs.groups[name]
+	if !ok {
+		rg = &RadioGroup{ID: name}
+		rgs.groups[name] = rg
+		rgs.names = append(rgs.names, name)
+	}
+
+	rb := RadioButton{
+		Rect:        rect,
+		ID:          title.String,
+		Value:       match[2],
+		TabSequence: getTabSequenceFromID(id),
+	}
+
+	for _, other := range rg.Buttons {
+		if other.Value == rb.Value {
+			return errors.New(fmt.Sprintf("radio group %s has more than one button with value %s", name, rb.Value))
+		}
+	}
+
+	if desc != nil && len(strings.TrimSpace(desc.String)) > 0 {
+		var options CheckBoxOptions
+		err := json.Unmarshal([]byte(desc.String), &options)
+		if err != nil {
+			return err
+		}
+		if options.Checked {
+			if rg.Default != "" {
+				return errors.New(fmt.Sprintf("radio group %s has more than one button checked", name))
+			}
+			rg.Default = rb.Value
+		}
+	}
+
+	rg.Buttons = append(rg.Buttons, rb)
+
+	return nil
+}
+
+// sorted returns the groups in tab order, with the buttons within each
+// group also in tab order. A group takes its place in the tab order from
+// its earliest button.
+func (rgs *radioGroups) sorted() []RadioGroup {
+
+	var groups []RadioGroup
+
+	for _, name := range rgs.names {
+
+		rg := *rgs.groups[name]
+
+		sort.SliceStable(rg.Buttons, func(i, j int) bool {
+			return rg.Buttons[i].TabSequence < rg.Buttons[j].TabSequence
+		})
+
+		rg.TabSequence = rg.Buttons[0].TabSequence
+
+		groups = append(groups, rg)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].TabSequence < groups[j].TabSequence
+	})
+
+	return groups
+}
+
+// UnmarshalChoiceOptions reads the choices for a dropdown or combobox.
+// There must be at least one choice, and unless the field is editable,
+// any default must be one of them.
+func UnmarshalChoiceOptions(id, properties string, editable bool) (ChoiceOptions, error) {
+
+	var options ChoiceOptions
+
+	if len(strings.TrimSpace(properties)) == 0 {
+		return options, errors.New(fmt.Sprintf("choice field %s has no description, so no choices", id))
+	}
+
+	err := json.Unmarshal([]byte(properties), &options)
+	if err != nil {
+		return options, err
+	}
+
+	if len(options.Choices) == 0 {
+		return options, errors.New(fmt.Sprintf("choice field %s has no choices", id))
+	}
+
+	if options.Default == "" || editable {
+		return options, nil
+	}
+
+	for _, choice := range options.Choices {
+		if choice == options.Default {
+			return options, nil
+		}
+	}
+
+	return options, errors.New(fmt.Sprintf("choice field %s has default %q which is not one of its choices", id, options.Default))
+}
+
 func ApplyDocumentUnits(svg *Csvg__svg, ladder *Ladder) error {
 
 	// iterate through the structure applying the conversion from
-	// document units to points
+	// user units to points
 
 	//note we do NOT apply the modification to ladder.DIM because this has its own
 	//units in it and has already been handled.
 
-	units := svg.Cnamedview__sodipodi.AttrInkscapeSpacedocument_dash_units
-
-	sf := float64(1)
-
-	switch units {
-	case "mm":
-		sf = geo.PPMM
-	case "px":
-		sf = geo.PPPX
-	case "pt":
-		sf = 1
-	case "in":
-		sf = geo.PPIN
+	sf, err := getUserUnitTransform(svg)
+	if err != nil {
+		return err
 	}
 
-	ladder.Anchor.X = sf * ladder.Anchor.X
-	ladder.Anchor.Y = sf * ladder.Anchor.Y
+	ladder.Anchor = sf.Apply(ladder.Anchor)
 
 	for idx, tf := range ladder.TextFields {
 		err := scaleTextFieldUnits(&tf, sf)
@@ -393,47 +885,88 @@ func ApplyDocumentUnits(svg *Csvg__svg, ladder *Ladder) error {
 		ladder.Placeholders[idx] = tf
 	}
 
+	for idx, cb := range ladder.CheckBoxes {
+		cb.Rect = scaleRect(cb.Rect, sf)
+		ladder.CheckBoxes[idx] = cb
+	}
+
+	for _, rg := range ladder.RadioGroups {
+		for idx, rb := range rg.Buttons {
+			rb.Rect = scaleRect(rb.Rect, sf)
+			rg.Buttons[idx] = rb
+		}
+	}
+
+	for idx, im := range ladder.Images {
+		rect := scaleRect(geo.Rect{Corner: im.Corner, Dim: im.Dim}, sf)
+		im.Corner = rect.Corner
+		im.Dim = rect.Dim
+		ladder.Images[idx] = im
+	}
+
+	for idx, dd := range ladder.DropDowns {
+		dd.Rect = scaleRect(dd.Rect, sf)
+		ladder.DropDowns[idx] = dd
+	}
+
+	for idx, cb := range ladder.ComboBoxes {
+		cb.Rect = scaleRect(cb.Rect, sf)
+		ladder.ComboBoxes[idx] = cb
+	}
+
 	return nil
 }
 
-func scaleTextFieldUnits(tf *TextField, sf float64) error {
+func scaleRect(rect geo.Rect, sf Matrix) geo.Rect {
+
+	return sf.ApplyRect(rect)
+}
+
+func scaleTextFieldUnits(tf *TextField, sf Matrix) error {
 	if tf == nil {
 		return errors.New("nil pointer to TextField")
 	}
 
-	tf.Rect.Corner.X = sf * tf.Rect.Corner.X
-	tf.Rect.Corner.Y = sf * tf.Rect.Corner.Y
-	tf.Rect.Dim.Width = sf * tf.Rect.Dim.Width
-	tf.Rect.Dim.Height = sf * tf.Rect.Dim.Height
+	tf.Rect = sf.ApplyRect(tf.Rect)
 
 	return nil
 }
 
-func scaleTextPrefillUnits(tf *TextPrefill, sf float64) error {
+func scaleTextPrefillUnits(tf *TextPrefill, sf Matrix) error {
 	if tf == nil {
 		return errors.New("nil pointer to TextField")
 	}
 
-	tf.Rect.Corner.X = sf * tf.Rect.Corner.X
-	tf.Rect.Corner.Y = sf * tf.Rect.Corner.Y
-	tf.Rect.Dim.Width = sf * tf.Rect.Dim.Width
-	tf.Rect.Dim.Height = sf * tf.Rect.Dim.Height
+	tf.Rect = sf.ApplyRect(tf.Rect)
 
 	return nil
 }
 
 func formRect(tf TextField, dim geo.Dim) []float64 {
 
-	return []float64{tf.Rect.Corner.X, dim.Height - tf.Rect.Corner.Y, (tf.Rect.Corner.X + tf.Rect.Dim.Width), dim.Height - (tf.Rect.Corner.Y + +tf.Rect.Dim.Height)}
+	return pdfRect(tf.Rect, dim)
+
+}
+
+// pdfRect converts a rect from svg coordinates (origin top left) into the
+// pdf coordinates (origin bottom left) of its top-left and bottom-right
+// corners, as used when making acroforms
+func pdfRect(rect geo.Rect, dim geo.Dim) []float64 {
+
+	return []float64{rect.Corner.X, dim.Height - rect.Corner.Y, (rect.Corner.X + rect.Dim.Width), dim.Height - (rect.Corner.Y + rect.Dim.Height)}
 
 }
 
 func getTabSequence(r *Crect__svg) int64 {
+	return getTabSequenceFromID(r.Id)
+}
+
+func getTabSequenceFromID(id string) int64 {
 	var TabSequence = regexp.MustCompile(`(?i:(tab|tab-))([0-9]+)`)
 	var SequenceNumber = regexp.MustCompile(`([0-9]+)`)
 	//TODO - combine regexp into one
 	var n int64
-	n, err := strconv.ParseInt(SequenceNumber.FindString(TabSequence.FindString(r.Id)), 10, 64)
+	n, err := strconv.ParseInt(SequenceNumber.FindString(TabSequence.FindString(id)), 10, 64)
 	if err != nil {
 		return int64(0)
 	}
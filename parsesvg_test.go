@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mattetti/filebuffer"
@@ -18,6 +21,51 @@ import (
 	"github.com/timdrysdale/unipdf/v3/model/optimize"
 )
 
+// fixturePage is the page size of a test fixture, in the units inkscape
+// writes it in, with its viewBox in user units, and any inkscape:page
+// elements of a multi-page document
+type fixturePage struct {
+	Width   string
+	Height  string
+	ViewBox string
+	Units   string
+	Pages   string
+}
+
+// ptPage is 100pt square, with a point to a user unit
+var ptPage = fixturePage{Width: "100pt", Height: "100pt", ViewBox: "0 0 100 100", Units: "pt"}
+
+// inkscapeSVG wraps the layers of a test fixture in the svg element,
+// namedview and metadata that inkscape writes, so that each fixture only
+// has the elements it is testing
+func inkscapeSVG(page fixturePage, layers string) string {
+
+	namedview := fmt.Sprintf(`  <sodipodi:namedview
+     id="base"
+     inkscape:document-units="%s" />`, page.Units)
+
+	if page.Pages != "" {
+		namedview = fmt.Sprintf(`  <sodipodi:namedview
+     id="base"
+     inkscape:document-units="%s">%s  </sodipodi:namedview>`, page.Units, page.Pages)
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<svg
+   xmlns:svg="http://www.w3.org/2000/svg"
+   xmlns="http://www.w3.org/2000/svg"
+   xmlns:xlink="http://www.w3.org/1999/xlink"
+   xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
+   xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+   width="%s"
+   height="%s"
+   viewBox="%s"
+   version="1.1">
+%s
+  <metadata
+     id="metadata1" />%s</svg>`, page.Width, page.Height, page.ViewBox, namedview, layers)
+}
+
 const testInkscapeSvg = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
 <!-- Created with Inkscape (http://www.inkscape.org/) -->
 
@@ -500,6 +548,8 @@ const textPrefillSVG = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
   </g>
 </svg>`
 
+var expectedTextPrefillWrap = true
+
 var expectedTextPrefill = &Ladder{
 	Anchor: geo.Point{X: 0, Y: 0},
 	Dim:    geo.Dim{Width: 141.73228346456693, Height: 141.73228346456693},
@@ -528,7 +578,7 @@ var expectedTextPrefill = &Ladder{
 				TextSize:            10,
 				LineHeight:          1,
 				Alignment:           "left",
-				EnableWrap:          true,
+				EnableWrap:          &expectedTextPrefillWrap,
 				WrapWidth:           50,
 				Angle:               0,
 				AbsolutePositioning: false,
@@ -796,3 +846,494 @@ func writeParsedGeometry(svg []byte, img *creator.Image, pageFilename string, c
 
 	pdfWriter.Write(of)
 }
+
+var nestedGroupsSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="textfields"
+     inkscape:groupmode="layer"
+     id="layer1"
+     transform="translate(0,10)">
+    <g
+       id="g1"
+       transform="translate(5,0)">
+      <g
+         id="g2"
+         transform="translate(0,5)">
+        <rect
+           id="rect1-tab-2"
+           x="1"
+           y="1"
+           width="10"
+           height="10">
+          <title>deep</title>
+        </rect>
+      </g>
+    </g>
+    <g
+       inkscape:label="textprefills"
+       inkscape:groupmode="layer"
+       id="layer2">
+      <rect
+         id="rect2"
+         x="20"
+         y="20"
+         width="10"
+         height="10">
+        <desc>{"text":"hello","textSize":10}</desc>
+        <title>sublayer</title>
+      </rect>
+    </g>
+    <rect
+       id="rect3-tab-1"
+       x="50"
+       y="50"
+       width="10"
+       height="10">
+      <title>shallow</title>
+    </rect>
+  </g>
+`)
+
+func TestDefineLadderFromSVGNestedGroups(t *testing.T) {
+
+	ladder, err := DefineLadderFromSVG([]byte(nestedGroupsSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	if len(ladder.TextFields) != 2 {
+		t.Fatalf("Expected 2 textfields, got %d", len(ladder.TextFields))
+	}
+
+	if ladder.TextFields[0].ID != "shallow" || ladder.TextFields[1].ID != "deep" {
+		t.Errorf("Textfields not in tab order: %v", ladder.TextFields)
+	}
+
+	want := geo.Point{X: 6, Y: 16}
+	if ladder.TextFields[1].Rect.Corner != want {
+		t.Errorf("Nested transforms not accumulated: got %v, want %v", ladder.TextFields[1].Rect.Corner, want)
+	}
+
+	if len(ladder.TextPrefills) != 1 || ladder.TextPrefills[0].ID != "sublayer" {
+		t.Errorf("Sublayer should set layer membership: %v", ladder.TextPrefills)
+	}
+
+	want = geo.Point{X: 20, Y: 30}
+	if len(ladder.TextPrefills) == 1 && ladder.TextPrefills[0].Rect.Corner != want {
+		t.Errorf("Sublayer should inherit transform: got %v, want %v", ladder.TextPrefills[0].Rect.Corner, want)
+	}
+}
+
+var checkBoxesSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="checkboxes"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <rect
+       id="rect1-tab-2"
+       x="10"
+       y="40"
+       width="10"
+       height="10">
+      <desc>{"checked":true,"onValue":"Seen"}</desc>
+      <title>seen</title>
+    </rect>
+    <rect
+       id="rect2-tab-1"
+       x="10"
+       y="10"
+       width="10"
+       height="10">
+      <title>moderated</title>
+    </rect>
+  </g>
+`)
+
+func TestDefineLadderCheckBoxes(t *testing.T) {
+
+	ladder, err := DefineLadderFromSVG([]byte(checkBoxesSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	want := []CheckBox{
+		CheckBox{
+			Rect:        geo.Rect{Corner: geo.Point{X: 10, Y: 10}, Dim: geo.Dim{Width: 10, Height: 10}},
+			ID:          "moderated",
+			TabSequence: 1,
+			Options:     CheckBoxOptions{Checked: false, OnValue: "Yes"},
+		},
+		CheckBox{
+			Rect:        geo.Rect{Corner: geo.Point{X: 10, Y: 40}, Dim: geo.Dim{Width: 10, Height: 10}},
+			ID:          "seen",
+			Properties:  `{"checked":true,"onValue":"Seen"}`,
+			TabSequence: 2,
+			Options:     CheckBoxOptions{Checked: true, OnValue: "Seen"},
+		},
+	}
+
+	if !reflect.DeepEqual(ladder.CheckBoxes, want) {
+		t.Errorf("CheckBoxes do not match expected\n%v\n%v", want, ladder.CheckBoxes)
+	}
+}
+
+var choicesSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="dropdowns"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <rect
+       id="rect1"
+       x="10"
+       y="10"
+       width="40"
+       height="10">
+      <desc>{"choices":["Agree","Disagree"],"default":"Agree"}</desc>
+      <title>verdict</title>
+    </rect>
+  </g>
+  <g
+     inkscape:label="comboboxes"
+     inkscape:groupmode="layer"
+     id="layer2">
+    <rect
+       id="rect2"
+       x="10"
+       y="40"
+       width="40"
+       height="10">
+      <desc>{"choices":["Fine","Resubmit"],"default":"See comments"}</desc>
+      <title>outcome</title>
+    </rect>
+  </g>
+`)
+
+func TestDefineLadderChoices(t *testing.T) {
+
+	ladder, err := DefineLadderFromSVG([]byte(choicesSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	if len(ladder.DropDowns) != 1 || len(ladder.ComboBoxes) != 1 {
+		t.Fatalf("Expected one dropdown and one combobox, got %v %v", ladder.DropDowns, ladder.ComboBoxes)
+	}
+
+	want := ChoiceOptions{Choices: []string{"Agree", "Disagree"}, Default: "Agree"}
+	if !reflect.DeepEqual(ladder.DropDowns[0].Options, want) {
+		t.Errorf("Dropdown options wrong\n%v\n%v", want, ladder.DropDowns[0].Options)
+	}
+
+	if ladder.ComboBoxes[0].Options.Default != "See comments" {
+		t.Errorf("Combobox should allow a default that is not a choice, got %v", ladder.ComboBoxes[0].Options)
+	}
+
+	_, err = UnmarshalChoiceOptions("verdict", `{"choices":["Agree"],"default":"Maybe"}`, false)
+	if err == nil {
+		t.Errorf("Dropdown default must be one of the choices")
+	}
+
+	_, err = UnmarshalChoiceOptions("verdict", ``, false)
+	if err == nil {
+		t.Errorf("Dropdown must have choices")
+	}
+}
+
+var radioButtonsSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="radiobuttons"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <circle
+       id="circle-tab-4"
+       cx="35"
+       cy="45"
+       r="5">
+      <title>q2-radio-b</title>
+    </circle>
+    <rect
+       id="rect-tab-2"
+       x="30"
+       y="10"
+       width="10"
+       height="10">
+      <desc>{"checked":true}</desc>
+      <title>q1-radio-3</title>
+    </rect>
+    <rect
+       id="rect-tab-1"
+       x="10"
+       y="10"
+       width="10"
+       height="10">
+      <title>q1-radio-2</title>
+    </rect>
+    <path
+       id="path-tab-3"
+       sodipodi:type="arc"
+       sodipodi:cx="15"
+       sodipodi:cy="45"
+       sodipodi:rx="5"
+       sodipodi:ry="5"
+       d="m 0,0">
+      <title>q2-radio-a</title>
+    </path>
+  </g>
+`)
+
+func TestDefineLadderRadioButtons(t *testing.T) {
+
+	ladder, err := DefineLadderFromSVG([]byte(radioButtonsSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	want := []RadioGroup{
+		RadioGroup{
+			ID:          "q1",
+			Default:     "3",
+			TabSequence: 1,
+			Buttons: []RadioButton{
+				RadioButton{
+					Rect:        geo.Rect{Corner: geo.Point{X: 10, Y: 10}, Dim: geo.Dim{Width: 10, Height: 10}},
+					ID:          "q1-radio-2",
+					Value:       "2",
+					TabSequence: 1,
+				},
+				RadioButton{
+					Rect:        geo.Rect{Corner: geo.Point{X: 30, Y: 10}, Dim: geo.Dim{Width: 10, Height: 10}},
+					ID:          "q1-radio-3",
+					Value:       "3",
+					TabSequence: 2,
+				},
+			},
+		},
+		RadioGroup{
+			ID:          "q2",
+			TabSequence: 3,
+			Buttons: []RadioButton{
+				RadioButton{
+					Rect:        geo.Rect{Corner: geo.Point{X: 10, Y: 40}, Dim: geo.Dim{Width: 10, Height: 10}},
+					ID:          "q2-radio-a",
+					Value:       "a",
+					TabSequence: 3,
+				},
+				RadioButton{
+					Rect:        geo.Rect{Corner: geo.Point{X: 30, Y: 40}, Dim: geo.Dim{Width: 10, Height: 10}},
+					ID:          "q2-radio-b",
+					Value:       "b",
+					TabSequence: 4,
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(ladder.RadioGroups, want) {
+		t.Errorf("Radio groups wrong\n%v\n%v", want, ladder.RadioGroups)
+	}
+
+	duplicate := strings.Replace(radioButtonsSVG, "q1-radio-2", "q1-radio-3", 1)
+	_, err = DefineLadderFromSVG([]byte(duplicate))
+	if err == nil {
+		t.Errorf("Expected an error for a repeated value in a radio group")
+	}
+
+	untitled := strings.Replace(radioButtonsSVG, "q2-radio-b", "q2-b", 1)
+	_, err = DefineLadderFromSVG([]byte(untitled))
+	if err == nil {
+		t.Errorf("Expected an error for a radio button title without a group")
+	}
+}
+
+func TestUnmarshalTextField(t *testing.T) {
+
+	tf := TextField{ID: "initials"}
+	err := UnmarshalTextField(&tf, "Enter your intials here")
+	if err != nil {
+		t.Errorf("Plain description should be a prefill, got error %v", err)
+	}
+	if tf.Prefill != "Enter your intials here" || !reflect.DeepEqual(tf.Options, TextFieldOptions{}) {
+		t.Errorf("Plain description should be a prefill, got %v", tf)
+	}
+
+	tf = TextField{ID: "mark"}
+	err = UnmarshalTextField(&tf, `{"prefill":"0","maxLen":2,"comb":true,"font":"Courier","fontSize":12,"align":"right","required":true,"tooltip":"Mark for Q1"}`)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	want := TextFieldOptions{
+		Prefill:  "0",
+		MaxLen:   2,
+		Comb:     true,
+		Font:     "Courier",
+		FontSize: 12,
+		Align:    "right",
+		Required: true,
+		Tooltip:  "Mark for Q1",
+	}
+
+	if tf.Prefill != "0" || !reflect.DeepEqual(tf.Options, want) {
+		t.Errorf("Textfield options wrong\n%v\n%v", want, tf.Options)
+	}
+
+	tf = TextField{ID: "mark"}
+	err = UnmarshalTextField(&tf, `{"min":0,"max":10,"step":0.5}`)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if !tf.Options.Numeric || tf.Options.Min == nil || *tf.Options.Min != 0 || tf.Options.Max == nil || *tf.Options.Max != 10 || tf.Options.Step != 0.5 {
+		t.Errorf("Numeric options wrong %v", tf.Options)
+	}
+
+	tf = TextField{ID: "total"}
+	err = UnmarshalTextField(&tf, `{"sum":["subtotal","qn-part-mark-*"]}`)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if !tf.Options.Numeric || !tf.Options.ReadOnly {
+		t.Errorf("Calculated fields should be numeric and read only %v", tf.Options)
+	}
+
+	for _, bad := range []string{
+		`{"sum":["bad"]}`,
+		`{"maxLen":2`,
+		`{"min":10,"max":0}`,
+		`{"step":-1}`,
+		`{"numeric":true,"prefill":"seven"}`,
+		`{"comb":true}`,
+		`{"maxLen":2,"comb":true,"multiline":true}`,
+		`{"maxLen":1,"prefill":"10"}`,
+		`{"font":"Comic Sans"}`,
+		`{"align":"justified"}`,
+	} {
+		tf = TextField{ID: "bad"}
+		if err := UnmarshalTextField(&tf, bad); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
+
+var viewBoxSVG = inkscapeSVG(fixturePage{Width: "100mm", Height: "50mm", ViewBox: "10 20 400 100", Units: "mm"}, `
+  <g
+     inkscape:label="textfields"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <rect
+       id="rect1"
+       x="50"
+       y="30"
+       width="40"
+       height="20">
+      <title>scaled</title>
+    </rect>
+  </g>
+`)
+
+func TestDefineLadderViewBox(t *testing.T) {
+
+	ladder, err := DefineLadderFromSVG([]byte(viewBoxSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	if len(ladder.TextFields) != 1 {
+		t.Fatalf("Expected one textfield, got %v", ladder.TextFields)
+	}
+
+	// x is 4 user units per mm, y is 2 user units per mm, offset by the origin
+	want := geo.Rect{Corner: geo.Point{X: 10 * geo.PPMM, Y: 5 * geo.PPMM}, Dim: geo.Dim{Width: 10 * geo.PPMM, Height: 10 * geo.PPMM}}
+	got := ladder.TextFields[0].Rect
+
+	if math.Abs(got.Corner.X-want.Corner.X) > 1e-9 ||
+		math.Abs(got.Corner.Y-want.Corner.Y) > 1e-9 ||
+		math.Abs(got.Dim.Width-want.Dim.Width) > 1e-9 ||
+		math.Abs(got.Dim.Height-want.Dim.Height) > 1e-9 {
+		t.Errorf("viewBox scaling wrong\n%v\n%v", want, got)
+	}
+
+	// same units in the viewBox as the page must give exactly the unit size
+	svg := Csvg__svg{Width: "210mm", Height: "297mm", AttrviewBox: "0 0 210 297"}
+	sf, err := getUserUnitTransform(&svg)
+	if err != nil || sf != (Matrix{geo.PPMM, 0, 0, geo.PPMM, 0, 0}) {
+		t.Errorf("Expected exact mm scaling, got %v %v", sf, err)
+	}
+}
+
+var inkscapePagesSVG = inkscapeSVG(fixturePage{Width: "200mm", Height: "100mm", ViewBox: "0 0 200 100", Units: "mm", Pages: `
+    <inkscape:page
+       x="0"
+       y="0"
+       width="100"
+       height="100"
+       id="page1"
+       inkscape:label="page-static-front" />
+    <inkscape:page
+       x="100"
+       y="0"
+       width="100"
+       height="50"
+       id="page2"
+       inkscape:label="page-dynamic-back" />
+`}, `
+  <g
+     inkscape:label="anchors"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <path
+       id="path1"
+       sodipodi:type="arc"
+       sodipodi:cx="10"
+       sodipodi:cy="20">
+      <title>ref-anchor</title>
+    </path>
+    <path
+       id="path2"
+       sodipodi:type="arc"
+       sodipodi:cx="130"
+       sodipodi:cy="5">
+      <title>svg-mark-header</title>
+    </path>
+  </g>
+`)
+
+func TestDefineLayoutInkscapePages(t *testing.T) {
+
+	layout, err := DefineLayoutFromSVG([]byte(inkscapePagesSVG))
+	if err != nil {
+		t.Fatalf("Error defining layout %v", err)
+	}
+
+	mm := geo.PPMM // not a constant, so the products round as in the parser
+
+	want := map[string]geo.Dim{
+		"front": geo.Dim{Width: 100 * mm, Height: 100 * mm},
+		"back":  geo.Dim{Width: 100 * mm, Height: 50 * mm, DynamicWidth: true},
+	}
+
+	if !reflect.DeepEqual(want, layout.PageDims) {
+		t.Errorf("Page dims wrong\n%v\n%v", want, layout.PageDims)
+	}
+
+	// anchors are relative to the page they are drawn on
+	if !reflect.DeepEqual(layout.Anchor, geo.Point{X: 10 * mm, Y: 20 * mm}) {
+		t.Errorf("Reference anchor wrong %v", layout.Anchor)
+	}
+
+	got := layout.Anchors["svg-mark-header"]
+	if math.Abs(got.X-30*mm) > 1e-9 || math.Abs(got.Y-5*mm) > 1e-9 {
+		t.Errorf("Anchor on second page wrong %v", got)
+	}
+}
+
+func TestDefineLayoutUnlabeledPage(t *testing.T) {
+
+	unlabeled := strings.Replace(inkscapePagesSVG, `inkscape:label="page-dynamic-back"`, "", 1)
+
+	_, err := DefineLayoutFromSVG([]byte(unlabeled))
+
+	var ee *ElementError
+	if !errors.As(err, &ee) || ee.ID != "page2" {
+		t.Errorf("Expected an error naming the unlabeled page, got %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+package parsesvg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+)
+
+// where a placeholder gets its contents from
+const (
+	SourceCourse         = "course"         // SpreadContents.CourseCode
+	SourceDiet           = "diet"           // SpreadContents.ExamDiet
+	SourceCandidate      = "candidate"      // SpreadContents.Candidate
+	SourceMarker         = "marker"         // SpreadContents.Marker
+	SourceExam           = "exam"           // SpreadContents.Exam
+	SourcePreviousFields = "previousFields" // a textfield for each of SpreadContents.PreviousFields
+	SourcePartName       = "partName"       // the name of each part of the paper
+	SourcePartMarks      = "partMarks"      // the marks available for each part
+	SourcePartMark       = "partMark"       // a mark box for each part
+	SourceTotal          = "total"          // a total, on the row after the last part
+)
+
+// PlaceholderOptions say what a placeholder is filled with when the
+// spread is rendered, and are read from a JSON object in its description,
+// e.g. {"source":"course","text":{"textSize":20}}. Sources with one value
+// make a prefill, and the rest make textfields, repeated for each part,
+// or previous field, in the direction given. A placeholder that has no
+// JSON description is only used if it has one of the titles that were
+// used before placeholders could be configured, e.g. qn-part-mark.
+type PlaceholderOptions struct {
+	Source    string    `json:"source"`
+	ID        string    `json:"id"`        // of what is made, numbered if repeated; defaults to the title
+	Text      Paragraph `json:"text"`      // style of a prefill; textSize defaults to 12
+	Prefix    string    `json:"prefix"`    // put before the value, e.g. "/" for the marks available
+	Suffix    string    `json:"suffix"`    // put after the value
+	Direction string    `json:"direction"` // of repeats; down (default), up, left or right
+	Pitch     float64   `json:"pitch"`     // between repeats, as a multiple of the placeholder's size; defaults to 1.2
+	Offset    geo.Point `json:"offset"`    // nudge, in points, e.g. {"x":0,"y":5}
+	Chrome    string    `json:"chrome"`    // image drawn under each mark box, found as the ladder chrome is
+	Sum       []string  `json:"sum"`       // the fields a total adds up, as for textfields
+	Spreads   []string  `json:"spreads"`   // only fill in these spreads, if any are given
+	ladders   []string  // only fill in the ladders at these anchors, if any are given
+}
+
+var placeholderSources = map[string]bool{
+	SourceCourse: true, SourceDiet: true, SourceCandidate: true, SourceMarker: true, SourceExam: true,
+	SourcePreviousFields: true, SourcePartName: true, SourcePartMarks: true, SourcePartMark: true, SourceTotal: true,
+}
+
+var placeholderDirections = map[string]geo.Point{
+	"down":  geo.Point{X: 0, Y: 1},
+	"up":    geo.Point{X: 0, Y: -1},
+	"right": geo.Point{X: 1, Y: 0},
+	"left":  geo.Point{X: -1, Y: 0},
+}
+
+// headerLadders are the only ladders the script info was filled in for,
+// before placeholders could be configured
+var headerLadders = []string{"svg-mark-header", "svg-moderate-header"}
+
+// legacyPlaceholders are the placeholders that were filled in before they
+// could be configured, so that existing designs render as they did
+var legacyPlaceholders = map[string]PlaceholderOptions{
+	"script-info-course":  {Source: SourceCourse, Text: Paragraph{TextSize: 20, Alignment: "left"}, ladders: headerLadders},
+	"script-info-diet":    {Source: SourceDiet, Text: Paragraph{TextSize: 20, Alignment: "left"}, ladders: headerLadders},
+	"script-info-student": {Source: SourceCandidate, Text: Paragraph{TextSize: 20, Alignment: "left"}, ladders: headerLadders},
+	"marker-id":           {Source: SourceMarker, Text: Paragraph{TextSize: 20, Alignment: "center"}, Spreads: []string{"mark"}},
+	"prev-fields":         {Source: SourcePreviousFields, ID: "prevfield", Direction: "up"},
+	"qn-part-name":        {Source: SourcePartName, Text: Paragraph{TextSize: 14, Alignment: "right"}},
+	"qn-part-total":       {Source: SourcePartMarks, Text: Paragraph{TextSize: 12}, Prefix: "/", Offset: geo.Point{X: 0, Y: 5}},
+	"qn-part-mark":        {Source: SourcePartMark, Chrome: "som/markbox.png"},
+	"qn-part-moderate":    {Source: SourcePartMark, Chrome: "som/markbox.png"},
+}
+
+// UnmarshalPlaceholder reads the options for a placeholder from its
+// description, which the ladder keeps as its Prefill. It returns false if
+// the placeholder isn't to be filled in.
+func UnmarshalPlaceholder(tf TextField) (PlaceholderOptions, bool, error) {
+
+	var options PlaceholderOptions
+
+	switch {
+
+	case strings.HasPrefix(strings.TrimSpace(tf.Prefill), "{"):
+		err := json.Unmarshal([]byte(tf.Prefill), &options)
+		if err != nil {
+			return options, false, errors.New(fmt.Sprintf("placeholder %s has a description that looks like JSON but isn't: %v", tf.ID, err))
+		}
+
+	case strings.HasPrefix(tf.ID, "qn-total-"):
+		// a total of whichever kind of box it is named for, e.g. qn-total-mark
+		kind := strings.TrimPrefix(tf.ID, "qn-total-")
+		options = PlaceholderOptions{Source: SourceTotal, Sum: []string{"qn-part-" + kind + "-*"}}
+
+	default:
+		legacy, ok := legacyPlaceholders[tf.ID]
+		if !ok {
+			return options, false, nil
+		}
+		options = legacy
+	}
+
+	if !placeholderSources[options.Source] {
+		return options, false, errors.New(fmt.Sprintf("placeholder %s has source %q, which is not one of the sources that can be used", tf.ID, options.Source))
+	}
+
+	if options.ID == "" {
+		options.ID = tf.ID
+	}
+
+	if options.Direction == "" {
+		options.Direction = "down"
+	}
+
+	if _, ok := placeholderDirections[options.Direction]; !ok {
+		return options, false, errors.New(fmt.Sprintf("placeholder %s has direction %s, which should be down, up, left or right", tf.ID, options.Direction))
+	}
+
+	if options.Pitch < 0 {
+		return options, false, errors.New(fmt.Sprintf("placeholder %s can't have a negative pitch", tf.ID))
+	}
+
+	if options.Pitch == 0 {
+		options.Pitch = 1.2
+	}
+
+	if options.Text.TextSize == 0 {
+		options.Text.TextSize = 12
+	}
+
+	if options.Source == SourceTotal && len(options.Sum) == 0 {
+		return options, false, errors.New(fmt.Sprintf("placeholder %s is a total, so needs a sum", tf.ID))
+	}
+
+	return options, true, nil
+}
+
+// inSpread is whether the placeholder is filled in for the named spread
+func (p PlaceholderOptions) inSpread(name string) bool {
+
+	if len(p.Spreads) == 0 {
+		return true
+	}
+
+	for _, spread := range p.Spreads {
+		if spread == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inLadder is whether the placeholder is filled in for the ladder at the
+// named anchor, e.g. svg-mark-header
+func (p PlaceholderOptions) inLadder(name string) bool {
+
+	if len(p.ladders) == 0 {
+		return true
+	}
+
+	for _, ladder := range p.ladders {
+		if ladder == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// localSum limits each entry of a total's sum that ends in * to the
+// fields made for the same ladder, if it matches any, so that a total
+// doesn't also add up the mark boxes of another ladder on the page
+func localSum(sum []string, made []string) []string {
+
+	var local []string
+
+	for _, pattern := range sum {
+
+		var matches []string
+
+		if strings.HasSuffix(pattern, "*") {
+			for _, id := range made {
+				if strings.HasPrefix(id, strings.TrimSuffix(pattern, "*")) {
+					matches = append(matches, id)
+				}
+			}
+		}
+
+		if len(matches) == 0 {
+			local = append(local, pattern)
+			continue
+		}
+
+		local = append(local, matches...)
+	}
+
+	return local
+}
+
+// repeat moves the rect along by n repeats
+func (p PlaceholderOptions) repeat(rect geo.Rect, n int) geo.Rect {
+
+	d := placeholderDirections[p.Direction]
+
+	step := geo.Point{
+		X: d.X * rect.Dim.Width * p.Pitch * float64(n),
+		Y: d.Y * rect.Dim.Height * p.Pitch * float64(n),
+	}
+
+	rect.Corner = TranslatePosition(step, rect.Corner)
+
+	return rect
+}
+
+func (p PlaceholderOptions) prefill(id string, rect geo.Rect, value string) TextPrefill {
+
+	text := p.Text
+	text.Text = p.Prefix + value + p.Suffix
+
+	return TextPrefill{Rect: rect, ID: id, Text: text}
+}
+
+// placed is a placeholder that is being filled in, at its place in the spread
+type placed struct {
+	options PlaceholderOptions
+	rect    geo.Rect
+}
+
+// fillPlaceholders adds what each placeholder in the ladder at the named
+// anchor is filled with to the spread, with the ladder's top left corner
+// at corner. The parts are filled in row by row, so that tab order runs
+// down the paper.
+func (s *Spread) fillPlaceholders(name string, ladder *Ladder, corner geo.Point, contents SpreadContents, parts []*PaperStructure, templates templateSource) error {
+
+	values := map[string]string{
+		SourceCourse:    contents.CourseCode,
+		SourceDiet:      contents.ExamDiet,
+		SourceCandidate: contents.Candidate,
+		SourceMarker:    contents.Marker,
+		SourceExam:      contents.Exam,
+	}
+
+	var perPart, totals []placed
+
+	for _, tp := range ladder.Placeholders {
+
+		options, ok, err := UnmarshalPlaceholder(tp)
+		if err != nil {
+			return err
+		}
+
+		if !ok || !options.inSpread(s.Name) || !options.inLadder(name) {
+			continue
+		}
+
+		rect := tp.Rect
+		rect.Corner = TranslatePosition(corner, rect.Corner)
+		rect.Corner = TranslatePosition(options.Offset, rect.Corner)
+
+		switch options.Source {
+
+		case SourceCourse, SourceDiet, SourceCandidate, SourceMarker, SourceExam:
+			if value := values[options.Source]; value != "" {
+				s.TextPrefills = append(s.TextPrefills, options.prefill(options.ID, rect, value))
+			}
+
+		case SourcePreviousFields:
+			// in order, so the fields are always in the same place
+			var names []string
+			for name := range contents.PreviousFields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for i, name := range names {
+				s.TextFields = append(s.TextFields, TextField{
+					Rect:    options.repeat(rect, i),
+					ID:      options.ID + "-" + name,
+					Prefill: contents.PreviousFields[name],
+				})
+			}
+
+		case SourcePartName, SourcePartMarks, SourcePartMark:
+			perPart = append(perPart, placed{options, rect})
+
+		case SourceTotal:
+			totals = append(totals, placed{options, rect})
+		}
+	}
+
+	// blank rows in the csv leave a gap, so the totals go after the last
+	// part, rather than after as many rows as there are parts
+	lastRow := -1
+
+	var made []string // ids of the mark boxes, for the totals
+
+	for pnum, part := range parts {
+
+		// a hack - if you leave a blank row in the csv you get an empty row.
+		if part == nil || part.Part == "" {
+			continue
+		}
+
+		lastRow = pnum
+
+		for _, p := range perPart {
+
+			rect := p.options.repeat(p.rect, pnum)
+			id := p.options.ID + "-" + strconv.Itoa(pnum)
+
+			switch p.options.Source {
+
+			case SourcePartName:
+				s.TextPrefills = append(s.TextPrefills, p.options.prefill(id, rect, part.Part))
+
+			case SourcePartMarks:
+				s.TextPrefills = append(s.TextPrefills, p.options.prefill(id, rect, strconv.Itoa(part.Marks)))
+
+			case SourcePartMark:
+				// only accept marks between zero and the marks for this part
+				min := 0.0
+				max := float64(part.Marks)
+				s.TextFields = append(s.TextFields, TextField{
+					Rect:    rect,
+					ID:      id,
+					Options: TextFieldOptions{Numeric: true, Min: &min, Max: &max},
+				})
+				made = append(made, id)
+
+				if p.options.Chrome != "" {
+					s.Images = append(s.Images, ImageInsert{
+						Filename: templates.findImage(p.options.Chrome, imageExtensions),
+						Corner:   rect.Corner,
+						Dim:      rect.Dim,
+						FS:       templates.fsys,
+					})
+				}
+			}
+		}
+	}
+
+	// totals go on the row after the last part, if there are any parts
+	if lastRow < 0 {
+		return nil
+	}
+
+	for _, p := range totals {
+		s.TextFields = append(s.TextFields, TextField{
+			Rect:    p.options.repeat(p.rect, lastRow+1),
+			ID:      p.options.ID,
+			Options: TextFieldOptions{Numeric: true, ReadOnly: true, Sum: localSum(p.options.Sum, made)},
+		})
+	}
+
+	return nil
+}
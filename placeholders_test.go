@@ -0,0 +1,184 @@
+package parsesvg
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestUnmarshalPlaceholder(t *testing.T) {
+
+	options, ok, err := UnmarshalPlaceholder(TextField{ID: "qn-part-total"})
+	if err != nil || !ok {
+		t.Fatalf("Expected legacy placeholder, got %v %v", ok, err)
+	}
+	if options.Source != SourcePartMarks || options.Prefix != "/" || options.Offset.Y != 5 || options.Pitch != 1.2 || options.ID != "qn-part-total" {
+		t.Errorf("Legacy options wrong %v", options)
+	}
+
+	options, ok, err = UnmarshalPlaceholder(TextField{ID: "qn-total-moderate"})
+	if err != nil || !ok || !reflect.DeepEqual(options.Sum, []string{"qn-part-moderate-*"}) {
+		t.Errorf("Legacy total wrong %v %v %v", options, ok, err)
+	}
+
+	options, ok, err = UnmarshalPlaceholder(TextField{ID: "student-no",
+		Prefill: `{"source":"candidate","id":"candidate","text":{"textSize":9,"alignment":"right"},"prefix":"No. ","spreads":["check"]}`})
+	if err != nil || !ok {
+		t.Fatalf("Expected placeholder, got %v %v", ok, err)
+	}
+	if options.ID != "candidate" || options.Text.TextSize != 9 || options.Direction != "down" || options.inSpread("mark") || !options.inSpread("check") {
+		t.Errorf("Options wrong %v", options)
+	}
+
+	_, ok, err = UnmarshalPlaceholder(TextField{ID: "my-note", Prefill: "just a note"})
+	if err != nil || ok {
+		t.Errorf("Expected unknown placeholder to be left alone, got %v %v", ok, err)
+	}
+
+	for _, desc := range []string{
+		`{"source":"weather"}`,
+		`{"source":"partName","direction":"sideways"}`,
+		`{"source":"total"}`,
+		`{"source":"partName",`,
+	} {
+		_, _, err = UnmarshalPlaceholder(TextField{ID: "bad", Prefill: desc})
+		if err == nil {
+			t.Errorf("%s: expected an error", desc)
+		}
+	}
+}
+
+func TestFillPlaceholders(t *testing.T) {
+
+	box := geo.Rect{Corner: geo.Point{X: 10, Y: 100}, Dim: geo.Dim{Width: 20, Height: 10}}
+
+	ladder := &Ladder{Placeholders: []TextField{
+		TextField{ID: "script-info-course", Rect: box},
+		TextField{ID: "marker-id", Rect: box},
+		TextField{ID: "qn-part-name", Rect: box},
+		TextField{ID: "qn-part-mark", Rect: box},
+		TextField{ID: "qn-total-mark", Rect: box},
+		TextField{ID: "ticks", Rect: box, Prefill: `{"source":"partMark","direction":"right","pitch":1.5,"chrome":"tick"}`},
+		TextField{ID: "note", Rect: box, Prefill: "not filled in"},
+	}}
+
+	parts := []*PaperStructure{
+		&PaperStructure{Part: "1a", Marks: 2},
+		&PaperStructure{},
+		&PaperStructure{Part: "1b", Marks: 4},
+		&PaperStructure{},
+	}
+
+	contents := SpreadContents{CourseCode: "MATH101", Marker: "abc"}
+
+	spread := &Spread{Name: "moderate"}
+
+	// another ladder's mark boxes, which the total mustn't add up
+	other := &Ladder{Placeholders: []TextField{
+		TextField{ID: "more", Rect: box, Prefill: `{"source":"partMark","id":"qn-part-mark-more"}`},
+	}}
+
+	err := spread.fillPlaceholders("svg-moderate-side", other, geo.Point{X: 100, Y: 0}, contents, parts, templateSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spread.TextFields = nil
+
+	err = spread.fillPlaceholders("svg-moderate-header", ladder, geo.Point{X: 5, Y: 0}, contents, parts, templateSource{fsys: fstest.MapFS{"designs/tick.png": &fstest.MapFile{}}, dir: "designs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the marker is only filled in on the mark spread
+	var prefills []string
+	for _, tp := range spread.TextPrefills {
+		prefills = append(prefills, tp.ID+"="+tp.Text.Text)
+	}
+	if !reflect.DeepEqual(prefills, []string{"script-info-course=MATH101", "qn-part-name-0=1a", "qn-part-name-2=1b"}) {
+		t.Errorf("Prefills wrong %v", prefills)
+	}
+
+	if spread.TextPrefills[0].Text.TextSize != 20 || spread.TextPrefills[0].Rect.Corner.X != 15 {
+		t.Errorf("Course prefill wrong %v", spread.TextPrefills[0])
+	}
+
+	// parts row by row, then the totals, on the row after the last part,
+	// whatever blank rows come after it
+	var fields []string
+	for _, tf := range spread.TextFields {
+		fields = append(fields, tf.ID)
+	}
+	if !reflect.DeepEqual(fields, []string{"qn-part-mark-0", "ticks-0", "qn-part-mark-2", "ticks-2", "qn-total-mark"}) {
+		t.Errorf("Fields wrong %v", fields)
+	}
+
+	want := map[string]geo.Point{
+		"qn-part-mark-2": geo.Point{X: 15, Y: 100 + 2*10*1.2},
+		"ticks-2":        geo.Point{X: 15 + 2*20*1.5, Y: 100},
+		"qn-total-mark":  geo.Point{X: 15, Y: 100 + 3*10*1.2},
+	}
+	for _, tf := range spread.TextFields {
+		if corner, ok := want[tf.ID]; ok && tf.Rect.Corner != corner {
+			t.Errorf("%s at %v, expected %v", tf.ID, tf.Rect.Corner, corner)
+		}
+	}
+
+	if *spread.TextFields[2].Options.Max != 4 || !spread.TextFields[4].Options.ReadOnly {
+		t.Errorf("Field options wrong %v", spread.TextFields)
+	}
+
+	if sum := spread.TextFields[4].Options.Sum; !reflect.DeepEqual(sum, []string{"qn-part-mark-0", "qn-part-mark-2"}) {
+		t.Errorf("Total should only add up this ladder's mark boxes, got %v", sum)
+	}
+
+	var images []string
+	for _, im := range spread.Images {
+		images = append(images, im.Filename)
+	}
+	if !reflect.DeepEqual(images, []string{"designs/som/markbox.png", "designs/tick.png", "designs/som/markbox.png", "designs/tick.png"}) {
+		t.Errorf("Images wrong %v", images)
+	}
+}
+
+func TestFillPlaceholdersHeaderOnly(t *testing.T) {
+
+	box := geo.Rect{Corner: geo.Point{X: 10, Y: 100}, Dim: geo.Dim{Width: 20, Height: 10}}
+
+	ladder := &Ladder{Placeholders: []TextField{
+		TextField{ID: "script-info-course", Rect: box},
+		TextField{ID: "course", Rect: box, Prefill: `{"source":"course"}`},
+		TextField{ID: "qn-total-mark", Rect: box},
+	}}
+
+	contents := SpreadContents{CourseCode: "MATH101"}
+
+	spread := &Spread{Name: "mark"}
+
+	// the script info was only ever filled in on the header ladders, but
+	// placeholders with a description are filled in wherever they are
+	err := spread.fillPlaceholders("svg-mark-ladder", ladder, geo.Point{}, contents, nil, templateSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spread.TextPrefills) != 1 || spread.TextPrefills[0].ID != "course" {
+		t.Errorf("Expected only the configured course prefill, got %v", spread.TextPrefills)
+	}
+
+	// and there are no parts to total
+	if len(spread.TextFields) != 0 {
+		t.Errorf("Expected no total without any parts, got %v", spread.TextFields)
+	}
+
+	err = spread.fillPlaceholders("svg-mark-header", ladder, geo.Point{}, contents, nil, templateSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spread.TextPrefills) != 3 || spread.TextPrefills[1].ID != "script-info-course" {
+		t.Errorf("Expected the script info on the header ladder, got %v", spread.TextPrefills)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -43,7 +46,9 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 	pageNumber := contents.PageNumber
 	pdfOutputPath := contents.PdfOutputPath
 		
-	svgBytes, err := ioutil.ReadFile(svgLayoutPath)
+	templates := templateSource{fsys: contents.Templates, dir: path.Dir(svgLayoutPath)}
+
+	svgBytes, err := templates.readFile(svgLayoutPath)
 
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error opening layout file %s: %v\n", svgLayoutPath, err))
@@ -51,7 +56,7 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 
 	layout, err := DefineLayoutFromSVG(svgBytes)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Error obtaining layout from svg %s\n", svgLayoutPath))
+		return fmt.Errorf("Error obtaining layout from svg because %w", fileError(err, svgLayoutPath))
 	}
 	
 	//fmt.Println(layout)
@@ -97,6 +102,23 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 	// the layout engine will just add the amount of the previous image's size in the dynamic dimension
 	// We need to add the anchor position to the textfield positions (which are relative to that anchor)
 
+	templateContext := newTemplateContext(contents)
+
+	// ladders are tabbed through in reading order, top to bottom, then
+	// left to right, and named in case two ladders share an anchor
+	sort.Slice(svgFilenames, func(i, j int) bool {
+		a, b := layout.Anchors[svgFilenames[i]], layout.Anchors[svgFilenames[j]]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		return svgFilenames[i] < svgFilenames[j]
+	})
+
+	tab := int64(0) // last tab sequence used on the page
+
 	for _, svgname := range svgFilenames {
 
 	//fmt.Println(svgname)
@@ -107,17 +129,17 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 			corner = thisAnchor
 		}
 
-		svgfilename := fmt.Sprintf("%s.svg", layout.Filenames[svgname])
-		imgfilename := fmt.Sprintf("%s.png", layout.Filenames[svgname]) //TODO check again library is jpg-only?
+		svgfilename := templates.path(fmt.Sprintf("%s.svg", layout.Filenames[svgname]))
+		imgfilename := templates.findImage(layout.Filenames[svgname], chromeExtensions)
 
-		svgBytes, err := ioutil.ReadFile(svgfilename)
+		svgBytes, err := templates.readFile(svgfilename)
 		if err != nil {
 			return errors.New(fmt.Sprintf("Entity %s: error opening svg file %s", svgname, svgfilename))
 		}
 
 		ladder, err := DefineLadderFromSVG(svgBytes)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Ladder %s: Error defining ladder from svg because %v", svgname, err))
+			return fmt.Errorf("Ladder %s: Error defining ladder from svg because %w", svgname, fileError(err, svgfilename))
 		}
 
 		if ladder == nil {
@@ -130,158 +152,76 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 			Filename: imgfilename,
 			Corner:   corner,
 			Dim:      ladder.Dim,
+			FS:       templates.fsys,
+		}
+
+		if contents.VectorChrome {
+			image.Vector, err = DefineVectorChromeFromSVG(svgBytes)
+			if err != nil {
+				return fmt.Errorf("Ladder %s: Error drawing chrome from svg because %w", svgname, fileError(err, svgfilename))
+			}
 		}
 
 		spread.Images = append(spread.Images, image) //add chrome to list of images to include
 
-		//append TextFields to the Textfield list
-		for _, tf := range ladder.TextFields {
+		// append images placed in the ladder, which sit on top of its chrome
+		for _, im := range ladder.Images {
 
-			//shift the text field and add it to the list
-			//let engine take care of mangling name to suit page
-			tf.Rect.Corner = TranslatePosition(corner, tf.Rect.Corner)
-			spread.TextFields = append(spread.TextFields, tf)
+			im.Corner = TranslatePosition(corner, im.Corner)
+			spread.Images = append(spread.Images, templates.image(im))
 		}
-		//append TextPrefills to the TextPrefill list
-		for _, tp := range ladder.TextPrefills {
 
-			//shift the text field and add it to the list
-			//let engine take care of mangling name to suit page
-			tp.Rect.Corner = TranslatePosition(corner, tp.Rect.Corner)
-			spread.TextPrefills = append(spread.TextPrefills, tp)
+		fromLadder := spread.fieldCounts()
+
+		//append TextFields and TextPrefills to their lists, filling in
+		//any templates in them, before anything else is filled in
+		err = spread.addLadderText(ladder, corner, templateContext)
+		if err != nil {
+			return fmt.Errorf("Ladder %s: %w", svgname, fileError(err, svgfilename))
 		}
+		//append CheckBoxes to the CheckBox list
+		for _, cb := range ladder.CheckBoxes {
 
-		
-		// Add the script info to the placeholders
-		if svgname == "svg-mark-header" || svgname == "svg-moderate-header" {
-		
-			for _, tp := range ladder.Placeholders {
-				new_rect := tp.Rect
-				new_rect.Corner = TranslatePosition(corner, new_rect.Corner)
-				new_text_field := TextPrefill{Rect: new_rect, ID: tp.ID,
-											  Text:     Paragraph{Text: "",
-																  TextSize: 20,
-																  Alignment: "left"}} // TODO - get alignment to work
-				switch box_type := tp.ID; box_type {
-					case "script-info-course":
-						new_text_field.Text.Text = contents.CourseCode
-					case "script-info-diet":
-						new_text_field.Text.Text = contents.ExamDiet
-					case "script-info-student":
-						new_text_field.Text.Text = contents.Candidate
-					default:
-						// do nothing
-				}
-				spread.TextPrefills = append(spread.TextPrefills, new_text_field)
-			
-				if tp.ID == "script-info-course" {
-				}
-			}
+			cb.Rect.Corner = TranslatePosition(corner, cb.Rect.Corner)
+			spread.CheckBoxes = append(spread.CheckBoxes, cb)
 		}
-		
-		// Add the Marker ID to the placeholder
-		if spread.Name == "mark" && contents.Marker != "" {
-		
-			for _, tp := range ladder.Placeholders {
-				if tp.ID == "marker-id" {
-					new_rect := tp.Rect
-					new_rect.Corner = TranslatePosition(corner, new_rect.Corner)
-					new_text_field := TextPrefill{Rect: new_rect,
-												  ID:       "marker-id",
-												  Text:     Paragraph{Text: contents.Marker,
-																	  TextSize: 20,
-																	  Alignment: "center"}} // TODO - get alignment to work
-					spread.TextPrefills = append(spread.TextPrefills, new_text_field)
-				}
-			}
+		//append DropDowns and ComboBoxes to their lists
+		for _, dd := range ladder.DropDowns {
+
+			dd.Rect.Corner = TranslatePosition(corner, dd.Rect.Corner)
+			spread.DropDowns = append(spread.DropDowns, dd)
 		}
-		
-		// Write any existing fields into the file
-		if len(contents.PreviousFields) > 0 {
-			//fmt.Println(contents.PreviousFields)
-			
-			base_rect := geo.Rect{}
-			for _, tp := range ladder.Placeholders {
-				if tp.ID == "prev-fields" {
-					base_rect = tp.Rect
-				}
-			}
-			
-			fcount := 0
-			for fname, fvalue := range contents.PreviousFields {
-				//fmt.Println("Field",fcount,fname,fvalue)
-									
-				new_rect := base_rect
-				new_rect.Corner = TranslatePosition(corner, new_rect.Corner)
-				new_rect.Corner = TranslatePosition(geo.Point{X:0, Y:new_rect.Dim.Height * float64(fcount) * -1.2}, new_rect.Corner)
-				
-				new_text_field := TextField{Rect: new_rect,
-											ID:         "prevfield-"+fname,
-											Prefill:	fvalue}
-				spread.TextFields = append(spread.TextFields, new_text_field)
-				fcount++
-			}
+		for _, cb := range ladder.ComboBoxes {
+
+			cb.Rect.Corner = TranslatePosition(corner, cb.Rect.Corner)
+			spread.ComboBoxes = append(spread.ComboBoxes, cb)
 		}
-		
-	
-		// Try out adding extra stuff
-		if (len(parts_and_marks) > 0) {
-			for pnum, part := range parts_and_marks {
-			//	fmt.Println("Part: ",part.Part, pnum)
-			//	fmt.Println("   ",part.Marks, " marks")
-				// a hack - if you leave a blank row in the csv you get an empty row.
-				// TODO - see if this can work with "|| part.Marks is empty", and move it to the qn-part-mark case below,
-				// so that only the form field is omitted if one of parts/marks is not specified - this could allow (part,marks) = (Q1,) to give a "question heading" effect
-				if part.Part == "" { 
-					continue
-				}
-				for _, tp := range ladder.Placeholders {
-					new_rect := tp.Rect
-					new_rect.Corner = TranslatePosition(corner, new_rect.Corner)
-					new_rect.Corner = TranslatePosition(geo.Point{X:0, Y:new_rect.Dim.Height * float64(pnum) * 1.2}, new_rect.Corner)
-					switch box_type := tp.ID; box_type {
-					case "qn-part-name":
-						new_text_field := TextPrefill{Rect: new_rect,
-													  ID:       "qn-part-name-"+strconv.Itoa(pnum),
-													  Text:     Paragraph{Text: part.Part,
-																		  TextSize: 14,
-																		  Alignment: "right"}} // TODO - get alignment to work
-						spread.TextPrefills = append(spread.TextPrefills, new_text_field)
-					case "qn-part-total":
-						// nudge it down a little
-						new_rect.Corner = TranslatePosition(geo.Point{X:0, Y:5}, new_rect.Corner)
-						new_text_field := TextPrefill{Rect: new_rect,
-													  ID:         "qn-part-total-"+strconv.Itoa(pnum),
-													  Text:     Paragraph{Text:"/"+strconv.Itoa(part.Marks), TextSize: 12}}
-						spread.TextPrefills = append(spread.TextPrefills, new_text_field)
-					case "qn-part-mark":
-						fallthrough
-					case "qn-part-moderate":
-						new_text_field := TextField{Rect: new_rect,
-													ID:         box_type+"-"+strconv.Itoa(pnum)}
-						spread.TextFields = append(spread.TextFields, new_text_field)
-						
-						// append chrome image to the images list
-						image := ImageInsert{
-							Filename: "som/markbox.png",  // TODO - make this customisable, e.g. using JSON in the placeholder's description
-							Corner:   new_rect.Corner,
-							Dim:      new_rect.Dim,
-						}
-
-						spread.Images = append(spread.Images, image) //add chrome to list of images to include
-						
-						//fmt.Println(new_text_field)
-						//fmt.Println("size of textfields: ", len(spread.TextFields))
-						//fmt.Println("\n")
-					
-						
-					default:
-						// do nothing
-					}
-				}
+		//append RadioGroups to the RadioGroup list
+		for _, rg := range ladder.RadioGroups {
+
+			buttons := []RadioButton{}
+			for _, rb := range rg.Buttons {
+				rb.Rect.Corner = TranslatePosition(corner, rb.Rect.Corner)
+				buttons = append(buttons, rb)
 			}
+			rg.Buttons = buttons
+			spread.RadioGroups = append(spread.RadioGroups, rg)
 		}
-		
+
+		tab = spread.followTabs(fromLadder, tab)
+
+		fromPlaceholders := spread.fieldCounts()
+
+		// fill in the placeholders, e.g. with the course code, or a mark box
+		// for each part, as their descriptions say
+		err = spread.fillPlaceholders(svgname, ladder, corner, contents, parts_and_marks, templates)
+		if err != nil {
+			return fmt.Errorf("Ladder %s: %w", svgname, fileError(err, svgfilename))
+		}
+
+		// placeholders come after the ladder's own fields, in the order made
+		tab = spread.followTabs(fromPlaceholders, tab)
+
 	//fmt.Println("\nend of "+svgname)	
 	//fmt.Println("size of prefills: ", len(spread.TextPrefills))
 	//fmt.Println("size of textfields: ", len(spread.TextFields))
@@ -302,15 +242,18 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 		}
 
 		imgfilename := imgname //in case not specified, e.g. previous image
+		var imgfs fs.FS
 
 		if filename, ok := layout.Filenames[imgname]; ok {
-			imgfilename = fmt.Sprintf("%s.jpg", filename)
+			imgfilename = templates.findImage(filename, imageExtensions)
+			imgfs = templates.fsys
 		}
 
 		// overwrite filename with dynamically supplied one, if supplied
 		if filename, ok := prefillImagePaths[imgname]; ok {
 
-			imgfilename = fmt.Sprintf("%s.jpg", filename)
+			imgfilename = templateSource{}.findImage(filename, imageExtensions)
+			imgfs = nil
 		}
 
 		corner := layout.Anchor
@@ -324,11 +267,26 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 			Filename: imgfilename,
 			Corner:   corner,
 			Dim:      layout.ImageDims[imgname],
+			FS:       imgfs,
 		}
 
 		spread.Images = append(spread.Images, image) //add chrome to list of images to include
 	}
 
+	// add the images placed directly in the layout, in name order so
+	// that overlapping images are always drawn the same way
+	var layoutImageNames []string
+	for k := range layout.Images {
+		if strings.Contains(k, spread.Name) {
+			layoutImageNames = append(layoutImageNames, k)
+		}
+	}
+	sort.Strings(layoutImageNames)
+
+	for _, k := range layoutImageNames {
+		spread.Images = append(spread.Images, templates.image(layout.Images[k]))
+	}
+
 	// Obtain the special "previous-image" which is flattened/rendered to image version of this page at the last step
 
 	previousImageAnchorName := fmt.Sprintf("img-previous-%s", spread.Name)
@@ -353,7 +311,7 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 
 	if strings.Compare(previousImage.Filename, "") != 0 {
 
-		img, err := c.NewImageFromFile(previousImage.Filename)
+		img, err := newImage(c, previousImage)
 
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error opening spread %s previous-image file %s: %v", spread.Name, previousImage.Filename, err))
@@ -395,7 +353,20 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 	}
 
 	for _, v := range spread.Images {
-		img, err := c.NewImageFromFile(v.Filename)
+
+		if v.Vector != nil {
+			corner := v.Corner
+			if spread.Dim.DynamicWidth {
+				corner.X = corner.X + spread.ExtraWidth
+			}
+			err := v.Vector.Draw(c, corner)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Error drawing vector chrome: %v", err))
+			}
+			continue
+		}
+
+		img, err := newImage(c, v)
 
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error opening image file %s: %s", v.Filename, err))
@@ -425,20 +396,17 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 		y = y + rowHeight
 	}
 
+	fonts := newPageFonts(c)
+
 	for _, tp := range spread.TextPrefills {
 		//update prefill contents from info given
 		if val, ok := contents.Prefills[pageNumber][tp.ID]; ok {
 			tp.Text.Text = val
 		}
-		// update our prefill text
-		p := c.NewParagraph(tp.Text.Text)
-		//fmt.Printf("Font size: %f", tp.Text.TextSize)
-		p.SetFontSize(tp.Text.TextSize)
-		p.SetPos(tp.Rect.Corner.X, tp.Rect.Corner.Y)
-		//fmt.Printf("prefill %f,%f\n", tp.Rect.Corner.X, tp.Rect.Corner.Y)
-		c.Draw(p)
-		//fmt.Println(tp)
-
+		err = tp.draw(c, fonts)
+		if err != nil {
+			return fmt.Errorf("Spread %s: %w", spreadName, err)
+		}
 	}
 
 	// This is the bit where we cross an internal boundary in the underlying library that has
@@ -478,9 +446,21 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 	  ******************************************************************************/
 	form := model.NewPdfAcroForm()
 
+	ids := []string{}
 	for _, tf := range spread.TextFields {
+		ids = append(ids, tf.ID)
+	}
+
+	// widgets are added to the page once they are all made, in tab order
+	widgets := pageWidgets{}
 
-		tfopt := annotator.TextFieldOptions{Value: tf.Prefill} //TODO - MaxLen?!
+	calculated := []*model.PdfField{}
+	calculatedNames := []string{}
+	sums := make(map[string][]string)
+
+	for _, tf := range spread.TextFields {
+
+		tfopt := annotator.TextFieldOptions{Value: tf.Prefill, MaxLen: tf.Options.MaxLen}
 		// TODO consider allowing a more templated mangling of the ID number
 		// For multi-student entries (although, OTH, there will be per-page ID data etc embedded too
 		// which may be more useful in this regard, rather than overloading the textfield id)
@@ -489,6 +469,17 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 		if spread.Dim.DynamicWidth {
 			tf.Rect.Corner.X = tf.Rect.Corner.X + spread.ExtraWidth
 		}
+
+		if len(tf.Options.Sum) > 0 {
+			sum, err := expandSum(tf.ID, tf.Options.Sum, ids)
+			if err != nil {
+				return err
+			}
+			for i, id := range sum {
+				sum[i] = fmt.Sprintf("page-%03d-%s", pageNumber, id)
+			}
+			tf.Options.Sum = sum
+		}
 		//fmt.Printf("Textfie %f %f\n", tf.Rect.Corner.X, tf.Rect.Corner.Y)
 		//fmt.Printf("formRe %v\n", formRect(tf, layout.Dim))
 		
@@ -539,17 +530,100 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 		*/
 		}
 		
-		textf, err := annotator.NewTextField(page, name, formRect(tf, layout.Dim), tfopt)
+		textf, err := annotator.NewTextField(page, name, formRect(tf, spread.Dim), tfopt)
 		if err != nil {
 			panic(err)
 		}
+
+		err = applyTextFieldOptions(form, textf, tf.Options)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error setting options for textfield %s: %v\n", name, err))
+		}
+
+		if len(tf.Options.Sum) > 0 {
+			calculated = append(calculated, textf.PdfField)
+			calculatedNames = append(calculatedNames, name)
+			sums[name] = tf.Options.Sum
+		}
+
 		*form.Fields = append(*form.Fields, textf.PdfField)
-		page.AddAnnotation(textf.Annotations[0].PdfAnnotation)
+		widgets.add(tf.TabSequence, textf.Annotations[0].PdfAnnotation)
 		
 		
 		
 	}
 
+	if len(calculated) > 0 {
+		err = setCalculationOrder(form, calculatedNames, calculated, sums)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cb := range spread.CheckBoxes {
+
+		name := fmt.Sprintf("page-%03d-%s", pageNumber, cb.ID)
+
+		if spread.Dim.DynamicWidth {
+			cb.Rect.Corner.X = cb.Rect.Corner.X + spread.ExtraWidth
+		}
+
+		cbopt := annotator.CheckboxFieldOptions{Checked: cb.Options.Checked}
+
+		checkbox, err := annotator.NewCheckboxField(page, name, pdfRect(cb.Rect, spread.Dim), cbopt)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error making checkbox %s: %v\n", name, err))
+		}
+
+		setOnState(checkbox, cb.Options.OnValue, cb.Options.Checked)
+
+		*form.Fields = append(*form.Fields, checkbox.PdfField)
+		widgets.add(cb.TabSequence, checkbox.Annotations[0].PdfAnnotation)
+	}
+
+	for _, dd := range spread.DropDowns {
+
+		if spread.Dim.DynamicWidth {
+			dd.Rect.Corner.X = dd.Rect.Corner.X + spread.ExtraWidth
+		}
+
+		err := addChoiceField(page, form, &widgets, pageNumber, dd, spread.Dim, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cb := range spread.ComboBoxes {
+
+		if spread.Dim.DynamicWidth {
+			cb.Rect.Corner.X = cb.Rect.Corner.X + spread.ExtraWidth
+		}
+
+		err := addChoiceField(page, form, &widgets, pageNumber, DropDown(cb), spread.Dim, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rg := range spread.RadioGroups {
+
+		if spread.Dim.DynamicWidth {
+			buttons := []RadioButton{}
+			for _, rb := range rg.Buttons {
+				rb.Rect.Corner.X = rb.Rect.Corner.X + spread.ExtraWidth
+				buttons = append(buttons, rb)
+			}
+			rg.Buttons = buttons
+		}
+
+		err := addRadioGroup(page, form, &widgets, pageNumber, rg, spread.Dim)
+		if err != nil {
+			return err
+		}
+	}
+
+	widgets.addTo(page)
+
 	err = pdfWriter.SetForms(form)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error: %v\n", err))
@@ -581,3 +655,92 @@ func RenderSpreadExtra(contents SpreadContents, parts_and_marks []*PaperStructur
 
 	return nil
 }
+
+// templateSource finds the files that a layout refers to, either in the
+// working directory (if fsys is nil) or relative to the layout in fsys
+type templateSource struct {
+	fsys fs.FS
+	dir  string
+}
+
+func (t templateSource) path(name string) string {
+
+	if t.fsys == nil {
+		return name
+	}
+
+	return path.Join(t.dir, name)
+}
+
+func (t templateSource) readFile(name string) ([]byte, error) {
+
+	if t.fsys == nil {
+		return ioutil.ReadFile(name)
+	}
+
+	return fs.ReadFile(t.fsys, name)
+}
+
+// image finds an image that was placed in a ladder or layout. An absolute
+// path, e.g. from a file:// link, is on this machine's disk, not in the
+// templates, so it is read from there, as it would be without them.
+func (t templateSource) image(v ImageInsert) ImageInsert {
+
+	if len(v.Data) == 0 && path.IsAbs(v.Filename) {
+		v.FS = nil
+		return v
+	}
+
+	if len(v.Data) == 0 {
+		v.Filename = t.path(v.Filename)
+		v.FS = t.fsys
+	}
+
+	return v
+}
+
+// findImage gives the filename of an image, using its own extension if it
+// has one, or else the first of the extensions that there is a file for
+func (t templateSource) findImage(name string, extensions []string) string {
+
+	if isImageExtension(path.Ext(name)) {
+		return t.path(name)
+	}
+
+	for _, ext := range extensions {
+		if t.exists(t.path(name + ext)) {
+			return t.path(name + ext)
+		}
+	}
+
+	// let opening it report the file we expected
+	return t.path(name + extensions[0])
+}
+
+func (t templateSource) exists(name string) bool {
+
+	var err error
+
+	if t.fsys == nil {
+		_, err = os.Stat(name)
+	} else {
+		_, err = fs.Stat(t.fsys, name)
+	}
+
+	return err == nil
+}
+
+func newImage(c *creator.Creator, v ImageInsert) (*creator.Image, error) {
+
+	data := v.Data
+
+	if len(data) == 0 {
+		var err error
+		data, err = templateSource{fsys: v.FS}.readFile(v.Filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newImageFromData(c, data)
+}
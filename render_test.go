@@ -1,9 +1,20 @@
 package parsesvg
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"testing/fstest"
 
+	"github.com/timdrysdale/geo"
 	"github.com/timdrysdale/pdfcomment"
+	"github.com/timdrysdale/unipdf/v3/core"
+	"github.com/timdrysdale/unipdf/v3/model"
 )
 
 func TestRenderImagePrefillBackwardsCompatibility(t *testing.T) {
@@ -118,3 +129,363 @@ func TestRenderImagePrefillReplaceImage(t *testing.T) {
 	}
 
 }
+
+func TestRenderSpreadFromFS(t *testing.T) {
+
+	// move the designs into a bundle, with paths relative to the layout
+	// instead of the working directory
+	templates := fstest.MapFS{}
+
+	entries, err := ioutil.ReadDir("./test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+
+		if entry.IsDir() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join("./test", entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data = bytes.ReplaceAll(data, []byte(">./test/"), []byte(">"))
+
+		templates["designs/"+entry.Name()] = &fstest.MapFile{Data: data}
+	}
+
+	contents := SpreadContents{
+		SvgLayoutPath:     "designs/layout-312pt-static-mark-dynamic-moderate-comment-static-check.svg",
+		SpreadName:        "mark",
+		PreviousImagePath: "./test/script.jpg",
+		PageNumber:        1,
+		PdfOutputPath:     "./test/render-mark-spread-fs.pdf",
+		Templates:         templates,
+	}
+
+	err = RenderSpreadExtra(contents, []*PaperStructure{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the same bundle can't be found from the working directory
+	contents.Templates = nil
+
+	err = RenderSpreadExtra(contents, []*PaperStructure{})
+	if err == nil {
+		t.Error("Expected an error opening the layout from the working directory")
+	}
+}
+
+func TestDefineLayoutFromReader(t *testing.T) {
+
+	f, err := os.Open("./test/layout-312pt-static-mark-dynamic-moderate-comment-static-check.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fromReader, err := DefineLayoutFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svgBytes, err := ioutil.ReadFile("./test/layout-312pt-static-mark-dynamic-moderate-comment-static-check.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromBytes, err := DefineLayoutFromSVG(svgBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromReader, fromBytes) {
+		t.Errorf("Layouts differ\n%v\n%v", fromReader, fromBytes)
+	}
+
+	_, err = DefineLadderFromReader(bytes.NewReader(make([]byte, DefaultMaxBytes+10)))
+	if !errors.Is(err, ErrSvgTooLarge) {
+		t.Errorf("Expected ErrSvgTooLarge, got %v", err)
+	}
+}
+
+var tabOrderLayoutSVG = inkscapeSVG(fixturePage{Width: "200pt", Height: "300pt", ViewBox: "0 0 200 300", Units: "pt"}, `
+  <g
+     inkscape:label="anchors"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <path sodipodi:type="arc" sodipodi:cx="0" sodipodi:cy="0" sodipodi:rx="3" sodipodi:ry="3" id="anchor1">
+      <title>ref-anchor</title>
+    </path>
+    <path sodipodi:type="arc" sodipodi:cx="10" sodipodi:cy="180" sodipodi:rx="3" sodipodi:ry="3" id="anchor2">
+      <title>svg-mark-more</title>
+      <desc>more</desc>
+    </path>
+    <path sodipodi:type="arc" sodipodi:cx="10" sodipodi:cy="10" sodipodi:rx="3" sodipodi:ry="3" id="anchor3">
+      <title>svg-mark-fields</title>
+      <desc>fields</desc>
+    </path>
+  </g>
+  <g
+     inkscape:label="pages"
+     inkscape:groupmode="layer"
+     id="layer2">
+    <rect id="page1" x="0" y="0" width="200" height="300">
+      <title>page-mark</title>
+    </rect>
+  </g>
+`)
+
+// widgetFieldName is the name of the field a widget belongs to, which is
+// on its parent, unless the field and widget are one and the same
+func widgetFieldName(a *model.PdfAnnotation) string {
+
+	dict, ok := core.GetDict(a.GetContainingPdfObject())
+	if !ok {
+		return ""
+	}
+
+	if parent, ok := core.GetDict(dict.Get("Parent")); ok {
+		dict = parent
+	}
+
+	name, _ := core.GetStringVal(dict.Get("T"))
+
+	return name
+}
+
+func TestRenderTabOrder(t *testing.T) {
+
+	box := func(y float64) geo.Rect {
+		return geo.Rect{Corner: geo.Point{X: 5, Y: y}, Dim: geo.Dim{Width: 20, Height: 10}}
+	}
+
+	// every kind of field, tabbed in a different order to the kinds
+	fields := &Ladder{
+		Dim: geo.Dim{Width: 100, Height: 160},
+		TextFields: []TextField{
+			TextField{Rect: box(10), ID: "first", TabSequence: 1},
+			TextField{Rect: box(50), ID: "third", TabSequence: 3},
+		},
+		RadioGroups: []RadioGroup{
+			RadioGroup{ID: "choice", TabSequence: 2, Buttons: []RadioButton{
+				RadioButton{Rect: box(30), ID: "choice-radio-yes", Value: "yes", TabSequence: 2},
+				RadioButton{Rect: box(40), ID: "choice-radio-no", Value: "no", TabSequence: 2},
+			}},
+		},
+		CheckBoxes: []CheckBox{
+			CheckBox{Rect: box(70), ID: "seen", TabSequence: 4, Options: CheckBoxOptions{OnValue: "Yes"}},
+		},
+		DropDowns: []DropDown{
+			DropDown{Rect: box(90), ID: "grade", TabSequence: 5, Options: ChoiceOptions{Choices: []string{"A", "B"}}},
+		},
+	}
+
+	// a second ladder, lower down the page, numbered from one again
+	more := &Ladder{
+		Dim:        geo.Dim{Width: 100, Height: 100},
+		TextFields: []TextField{TextField{Rect: box(10), ID: "last", TabSequence: 1}},
+	}
+
+	templates := fstest.MapFS{"designs/layout.svg": &fstest.MapFile{Data: []byte(tabOrderLayoutSVG)}}
+
+	for name, ladder := range map[string]*Ladder{"fields": fields, "more": more} {
+		var buf bytes.Buffer
+		err := WriteLadderSVG(&buf, ladder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates["designs/"+name+".svg"] = &fstest.MapFile{Data: buf.Bytes()}
+	}
+
+	contents := SpreadContents{
+		SvgLayoutPath: "designs/layout.svg",
+		SpreadName:    "mark",
+		PageNumber:    1,
+		PdfOutputPath: "./test/render-tab-order.pdf",
+		Templates:     templates,
+		VectorChrome:  true,
+	}
+
+	err := RenderSpreadExtra(contents, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(contents.PdfOutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, a := range annotations {
+		got = append(got, widgetFieldName(a))
+	}
+
+	want := []string{"page-001-first", "page-001-choice", "page-001-choice", "page-001-third",
+		"page-001-seen", "page-001-grade", "page-001-last"}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Widgets not in tab order\n%v\n%v", want, got)
+	}
+}
+
+// shortPageLayoutSVG has a second page that is half the height of the
+// document, with a ladder on it
+var shortPageLayoutSVG = inkscapeSVG(fixturePage{Width: "200mm", Height: "100mm", ViewBox: "0 0 200 100", Units: "mm", Pages: `
+    <inkscape:page x="0" y="0" width="100" height="100" id="page1" inkscape:label="page-front" />
+    <inkscape:page x="100" y="0" width="100" height="50" id="page2" inkscape:label="page-back" />
+`}, `
+  <g
+     inkscape:label="anchors"
+     inkscape:groupmode="layer"
+     id="layer1">
+    <path sodipodi:type="arc" sodipodi:cx="0" sodipodi:cy="0" sodipodi:rx="3" sodipodi:ry="3" id="anchor1">
+      <title>ref-anchor</title>
+    </path>
+    <path sodipodi:type="arc" sodipodi:cx="110" sodipodi:cy="10" sodipodi:rx="3" sodipodi:ry="3" id="anchor2">
+      <title>svg-back-fields</title>
+      <desc>fields</desc>
+    </path>
+  </g>
+`)
+
+func TestRenderShortPageFieldRect(t *testing.T) {
+
+	fields := &Ladder{
+		Dim: geo.Dim{Width: 100, Height: 100},
+		TextFields: []TextField{
+			TextField{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 10}, Dim: geo.Dim{Width: 20, Height: 10}}, ID: "answer", TabSequence: 1},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteLadderSVG(&buf, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := SpreadContents{
+		SvgLayoutPath: "designs/layout.svg",
+		SpreadName:    "back",
+		PageNumber:    1,
+		PdfOutputPath: "./test/render-short-page.pdf",
+		Templates: fstest.MapFS{
+			"designs/layout.svg": &fstest.MapFile{Data: []byte(shortPageLayoutSVG)},
+			"designs/fields.svg": &fstest.MapFile{Data: buf.Bytes()},
+		},
+		VectorChrome: true,
+	}
+
+	err = RenderSpreadExtra(contents, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(contents.PdfOutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(annotations) != 1 {
+		t.Fatalf("Expected one widget, got %d", len(annotations))
+	}
+
+	array, ok := core.GetArray(annotations[0].Rect)
+	if !ok {
+		t.Fatal("Expected the widget to have a rect")
+	}
+
+	rect, err := array.ToFloat64Array()
+	if err != nil || len(rect) != 4 {
+		t.Fatalf("Can't read the widget's rect %v %v", rect, err)
+	}
+
+	// flipped against the 50mm page, not the 100mm document
+	mm := geo.PPMM
+	x := 10*mm + 5
+	top := 50*mm - (10*mm + 10)
+
+	got := []float64{math.Min(rect[0], rect[2]), math.Max(rect[1], rect[3]), math.Max(rect[0], rect[2]), math.Min(rect[1], rect[3])}
+	want := []float64{x, top, x + 20, top - 10}
+
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Errorf("Widget rect wrong\n%v\n%v", want, got)
+			break
+		}
+	}
+}
+
+func TestRenderEmptySum(t *testing.T) {
+
+	// a total of the mark boxes, on a paper with no parts
+	fields := &Ladder{
+		Dim: geo.Dim{Width: 100, Height: 100},
+		TextFields: []TextField{
+			TextField{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 10}, Dim: geo.Dim{Width: 20, Height: 10}}, ID: "total", TabSequence: 1,
+				Options: TextFieldOptions{Numeric: true, ReadOnly: true, Sum: []string{"qn-part-mark-*"}}},
+		},
+	}
+
+	templates := fstest.MapFS{"designs/layout.svg": &fstest.MapFile{Data: []byte(tabOrderLayoutSVG)}}
+
+	for name, ladder := range map[string]*Ladder{"fields": fields, "more": &Ladder{Dim: geo.Dim{Width: 100, Height: 100}}} {
+		var buf bytes.Buffer
+		err := WriteLadderSVG(&buf, ladder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates["designs/"+name+".svg"] = &fstest.MapFile{Data: buf.Bytes()}
+	}
+
+	contents := SpreadContents{
+		SvgLayoutPath: "designs/layout.svg",
+		SpreadName:    "mark",
+		PageNumber:    1,
+		PdfOutputPath: "./test/render-empty-sum.pdf",
+		Templates:     templates,
+		VectorChrome:  true,
+	}
+
+	err := RenderSpreadExtra(contents, []*PaperStructure{})
+	if err != nil {
+		t.Errorf("A total of no parts should render, got %v", err)
+	}
+}
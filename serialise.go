@@ -0,0 +1,154 @@
+package parsesvg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// FormatVersion is saved with every ladder, layout and spread. It goes up
+// whenever the types change in a way that would make older files load
+// wrongly, so that they are rejected instead.
+const FormatVersion = 1
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+	ErrWrongKind          = errors.New("file holds a different kind of design")
+)
+
+// Format is how a design is saved. Loading works out which was used.
+type Format int
+
+const (
+	JSON Format = iota
+	YAML
+)
+
+// savedDesign is what is written to the file, so that the version sits
+// alongside whichever one of the designs was saved
+type savedDesign struct {
+	Version int     `json:"version"`
+	Ladder  *Ladder `json:"ladder,omitempty"`
+	Layout  *Layout `json:"layout,omitempty"`
+	Spread  *Spread `json:"spread,omitempty"`
+}
+
+// SaveLadder writes a parsed ladder, so that it can be loaded again with
+// LoadLadder instead of parsing the svg. Embedded image data is kept, but
+// the fs.FS an image was to be read from is not.
+func SaveLadder(w io.Writer, ladder *Ladder, format Format) error {
+	return saveDesign(w, savedDesign{Version: FormatVersion, Ladder: ladder}, format)
+}
+
+func SaveLayout(w io.Writer, layout *Layout, format Format) error {
+	return saveDesign(w, savedDesign{Version: FormatVersion, Layout: layout}, format)
+}
+
+func SaveSpread(w io.Writer, spread *Spread, format Format) error {
+	return saveDesign(w, savedDesign{Version: FormatVersion, Spread: spread}, format)
+}
+
+// LoadLadder reads a ladder written by SaveLadder, in either format
+func LoadLadder(r io.Reader) (*Ladder, error) {
+
+	saved, err := loadDesign(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.Ladder == nil {
+		return nil, fmt.Errorf("%w: expected a ladder", ErrWrongKind)
+	}
+
+	return saved.Ladder, nil
+}
+
+func LoadLayout(r io.Reader) (*Layout, error) {
+
+	saved, err := loadDesign(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.Layout == nil {
+		return nil, fmt.Errorf("%w: expected a layout", ErrWrongKind)
+	}
+
+	return saved.Layout, nil
+}
+
+func LoadSpread(r io.Reader) (*Spread, error) {
+
+	saved, err := loadDesign(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.Spread == nil {
+		return nil, fmt.Errorf("%w: expected a spread", ErrWrongKind)
+	}
+
+	return saved.Spread, nil
+}
+
+func saveDesign(w io.Writer, saved savedDesign, format Format) error {
+
+	// indented, so that changes to a design are easy to see in a diff
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case JSON:
+		data = append(data, '\n')
+	case YAML:
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New(fmt.Sprintf("unknown format %d", format))
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// loadDesign takes JSON as it is, and converts anything else from YAML.
+// Unknown fields are an error, to catch typos in hand-edited files.
+func loadDesign(r io.Reader) (savedDesign, error) {
+
+	saved := savedDesign{}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return saved, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return saved, err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&saved)
+	if err != nil {
+		return saved, err
+	}
+
+	if saved.Version < 1 || saved.Version > FormatVersion {
+		return saved, fmt.Errorf("%w: %d, but this version of parsesvg reads up to %d", ErrUnsupportedVersion, saved.Version, FormatVersion)
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,140 @@
+package parsesvg
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoadLadder(t *testing.T) {
+
+	svgBytes, err := ioutil.ReadFile("./test/sidebar-312pt-mark-ladder.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ladder, err := DefineLadderFromSVG(svgBytes)
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	chrome, err := DefineVectorChromeFromSVG(svgBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ladder.Images = append(ladder.Images, ImageInsert{Data: []byte("hello"), Dim: ladder.Dim, Vector: chrome})
+
+	for _, format := range []Format{JSON, YAML} {
+
+		var buf bytes.Buffer
+
+		err = SaveLadder(&buf, ladder, format)
+		if err != nil {
+			t.Fatalf("Error saving ladder %v", err)
+		}
+
+		got, err := LoadLadder(&buf)
+		if err != nil {
+			t.Fatalf("Error loading ladder %v", err)
+		}
+
+		if !reflect.DeepEqual(ladder, got) {
+			t.Errorf("Format %d: ladder changed\n%v\n%v", format, ladder, got)
+		}
+	}
+}
+
+func TestSaveLoadLayout(t *testing.T) {
+
+	svgBytes, err := ioutil.ReadFile("./test/layout-312pt-static-mark-dynamic-moderate-static-check-v2.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout, err := DefineLayoutFromSVG(svgBytes)
+	if err != nil {
+		t.Fatalf("Error defining layout %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	err = SaveLayout(&buf, layout, JSON)
+	if err != nil {
+		t.Fatalf("Error saving layout %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `"version": 1`) {
+		t.Errorf("Version not saved\n%s", buf.String())
+	}
+
+	saved := buf.String()
+
+	got, err := LoadLayout(&buf)
+	if err != nil {
+		t.Fatalf("Error loading layout %v", err)
+	}
+
+	if !reflect.DeepEqual(layout, got) {
+		t.Errorf("Layout changed\n%v\n%v", layout, got)
+	}
+
+	_, err = LoadLadder(strings.NewReader(saved))
+	if !errors.Is(err, ErrWrongKind) {
+		t.Errorf("Expected ErrWrongKind, got %v", err)
+	}
+}
+
+func TestSaveLoadSpread(t *testing.T) {
+
+	spread := &Spread{
+		Name:       "mark",
+		ExtraWidth: 12.5,
+		TextFields: []TextField{TextField{ID: "q1", Prefill: "0"}},
+		RadioGroups: []RadioGroup{RadioGroup{ID: "q2", Default: "b",
+			Buttons: []RadioButton{RadioButton{ID: "q2-a", Value: "a"}, RadioButton{ID: "q2-b", Value: "b"}}}},
+	}
+
+	var buf bytes.Buffer
+
+	err := SaveSpread(&buf, spread, YAML)
+	if err != nil {
+		t.Fatalf("Error saving spread %v", err)
+	}
+
+	got, err := LoadSpread(&buf)
+	if err != nil {
+		t.Fatalf("Error loading spread %v", err)
+	}
+
+	if !reflect.DeepEqual(spread, got) {
+		t.Errorf("Spread changed\n%v\n%v", spread, got)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{`{"layout":{"id":"old"}}`, ErrUnsupportedVersion},
+		{`{"version":2,"layout":{"id":"new"}}`, ErrUnsupportedVersion},
+		{`{"version":1,"ladder":{"id":"ladder"}}`, ErrWrongKind},
+	}
+
+	for _, test := range tests {
+		_, err := LoadLayout(strings.NewReader(test.input))
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.input, test.want, err)
+		}
+	}
+
+	_, err := LoadLayout(strings.NewReader(`{"version":1,"layout":{"idd":"typo"}}`))
+	if err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
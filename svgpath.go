@@ -0,0 +1,327 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+)
+
+// pathCommand is one step of a path, reduced to the operations that a pdf
+// content stream has: M (move), L (line), C (cubic bezier) and Z (close).
+// Points are absolute, in the user units of the path.
+type pathCommand struct {
+	Op     byte
+	Points []geo.Point
+}
+
+// how many numbers each svg path command takes
+var pathArgs = map[byte]int{
+	'M': 2, 'L': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'T': 2, 'A': 7, 'Z': 0,
+}
+
+// parsePathData reads the d attribute of a path into absolute moves, lines
+// and cubic beziers, converting relative commands, shorthand curves,
+// quadratic curves and elliptical arcs on the way
+func parsePathData(d string) ([]pathCommand, error) {
+
+	var commands []pathCommand
+
+	s := pathScanner{str: d}
+
+	var current, start, lastControl geo.Point
+	var lastOp byte
+
+	for {
+
+		s.skipSeparators()
+		if s.done() {
+			break
+		}
+
+		cmd := s.str[s.pos]
+		upper := cmd &^ 0x20 // upper case
+
+		n, ok := pathArgs[upper]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("unknown path command %q at %d", cmd, s.pos))
+		}
+		s.pos++
+
+		relative := cmd != upper
+
+		// commands repeat while there are numbers, e.g. "l 1,2 3,4"
+		for first := true; first || (n > 0 && s.numberNext()); first = false {
+
+			args := make([]float64, n)
+			for i := range args {
+				var err error
+				if upper == 'A' && (i == 3 || i == 4) {
+					args[i], err = s.flag()
+				} else {
+					args[i], err = s.number()
+				}
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("path command %q: %v", cmd, err))
+				}
+			}
+
+			// make the arguments absolute
+			if relative {
+				switch upper {
+				case 'H':
+					args[0] += current.X
+				case 'V':
+					args[0] += current.Y
+				case 'A':
+					args[5] += current.X
+					args[6] += current.Y
+				default:
+					for i := 0; i+1 < n; i += 2 {
+						args[i] += current.X
+						args[i+1] += current.Y
+					}
+				}
+			}
+
+			// a quadratic's control point is needed for T, so remember it
+			// separately from the cubic's
+			var quadControl geo.Point
+
+			switch upper {
+
+			case 'M':
+				current = geo.Point{X: args[0], Y: args[1]}
+				start = current
+				if first {
+					commands = append(commands, pathCommand{Op: 'M', Points: []geo.Point{current}})
+				} else {
+					// extra pairs after a move are lines
+					commands = append(commands, pathCommand{Op: 'L', Points: []geo.Point{current}})
+				}
+
+			case 'L', 'H', 'V':
+				switch upper {
+				case 'L':
+					current = geo.Point{X: args[0], Y: args[1]}
+				case 'H':
+					current.X = args[0]
+				case 'V':
+					current.Y = args[0]
+				}
+				commands = append(commands, pathCommand{Op: 'L', Points: []geo.Point{current}})
+
+			case 'C', 'S':
+				c1 := current
+				if upper == 'S' {
+					if lastOp == 'C' || lastOp == 'S' {
+						c1 = reflectPoint(lastControl, current)
+					}
+					args = append([]float64{c1.X, c1.Y}, args...)
+				}
+				c1 = geo.Point{X: args[0], Y: args[1]}
+				c2 := geo.Point{X: args[2], Y: args[3]}
+				end := geo.Point{X: args[4], Y: args[5]}
+				commands = append(commands, pathCommand{Op: 'C', Points: []geo.Point{c1, c2, end}})
+				lastControl = c2
+				current = end
+
+			case 'Q', 'T':
+				q := current
+				if upper == 'T' {
+					if lastOp == 'Q' || lastOp == 'T' {
+						q = reflectPoint(lastControl, current)
+					}
+					args = append([]float64{q.X, q.Y}, args...)
+				}
+				q = geo.Point{X: args[0], Y: args[1]}
+				end := geo.Point{X: args[2], Y: args[3]}
+				commands = append(commands, quadraticToCubic(current, q, end))
+				quadControl = q
+				current = end
+
+			case 'A':
+				end := geo.Point{X: args[5], Y: args[6]}
+				commands = append(commands, arcToCubics(current, args[0], args[1], args[2], args[3] != 0, args[4] != 0, end)...)
+				current = end
+
+			case 'Z':
+				commands = append(commands, pathCommand{Op: 'Z'})
+				current = start
+			}
+
+			if upper == 'Q' || upper == 'T' {
+				lastControl = quadControl
+			}
+
+			lastOp = upper
+		}
+	}
+
+	return commands, nil
+}
+
+func reflectPoint(p, about geo.Point) geo.Point {
+	return geo.Point{X: 2*about.X - p.X, Y: 2*about.Y - p.Y}
+}
+
+// a quadratic bezier is exactly a cubic with the control points two thirds
+// of the way to the quadratic's control point
+func quadraticToCubic(from, control, to geo.Point) pathCommand {
+	c1 := geo.Point{X: from.X + 2.0/3.0*(control.X-from.X), Y: from.Y + 2.0/3.0*(control.Y-from.Y)}
+	c2 := geo.Point{X: to.X + 2.0/3.0*(control.X-to.X), Y: to.Y + 2.0/3.0*(control.Y-to.Y)}
+	return pathCommand{Op: 'C', Points: []geo.Point{c1, c2, to}}
+}
+
+// arcToCubics approximates an svg elliptical arc with cubic beziers, of no
+// more than a quarter turn each, using the endpoint to centre conversion
+// in the implementation notes of the SVG spec (F.6.5)
+func arcToCubics(from geo.Point, rx, ry, angle float64, large, sweep bool, to geo.Point) []pathCommand {
+
+	if from == to {
+		return nil
+	}
+
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return []pathCommand{{Op: 'L', Points: []geo.Point{to}}}
+	}
+
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+
+	dx := (from.X - to.X) / 2
+	dy := (from.Y - to.Y) / 2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	// scale up radii that are too small to reach
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+
+	cx := cos*cx1 - sin*cy1 + (from.X+to.X)/2
+	cy := sin*cx1 + cos*cy1 + (from.Y+to.Y)/2
+
+	theta := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	end := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+	delta := end - theta
+
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	segments := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(segments)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+
+	// point on the ellipse, and its derivative, at parameter t
+	point := func(t float64) (geo.Point, geo.Point) {
+		st, ct := math.Sincos(t)
+		p := geo.Point{X: cx + rx*ct*cos - ry*st*sin, Y: cy + rx*ct*sin + ry*st*cos}
+		d := geo.Point{X: -rx*st*cos - ry*ct*sin, Y: -rx*st*sin + ry*ct*cos}
+		return p, d
+	}
+
+	var commands []pathCommand
+
+	p0, d0 := point(theta)
+	for i := 0; i < segments; i++ {
+		p1, d1 := point(theta + step*float64(i+1))
+		if i == segments-1 {
+			p1 = to // avoid a gap from rounding
+		}
+		c1 := geo.Point{X: p0.X + k*d0.X, Y: p0.Y + k*d0.Y}
+		c2 := geo.Point{X: p1.X - k*d1.X, Y: p1.Y - k*d1.Y}
+		commands = append(commands, pathCommand{Op: 'C', Points: []geo.Point{c1, c2, p1}})
+		p0, d0 = p1, d1
+	}
+
+	return commands
+}
+
+// pathScanner reads the numbers in path data, which can be run together,
+// e.g. "1.5.5-2" is 1.5, 0.5 and -2
+type pathScanner struct {
+	str string
+	pos int
+}
+
+func (s *pathScanner) done() bool {
+	return s.pos >= len(s.str)
+}
+
+func (s *pathScanner) skipSeparators() {
+	for !s.done() && strings.IndexByte(" \t\r\n,", s.str[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+func (s *pathScanner) numberNext() bool {
+	s.skipSeparators()
+	return !s.done() && strings.IndexByte("+-.0123456789", s.str[s.pos]) >= 0
+}
+
+func (s *pathScanner) number() (float64, error) {
+
+	s.skipSeparators()
+
+	start := s.pos
+	digits := func() {
+		for !s.done() && s.str[s.pos] >= '0' && s.str[s.pos] <= '9' {
+			s.pos++
+		}
+	}
+	sign := func() {
+		if !s.done() && (s.str[s.pos] == '+' || s.str[s.pos] == '-') {
+			s.pos++
+		}
+	}
+
+	sign()
+	digits()
+	if !s.done() && s.str[s.pos] == '.' {
+		s.pos++
+		digits()
+	}
+	if !s.done() && (s.str[s.pos] == 'e' || s.str[s.pos] == 'E') {
+		s.pos++
+		sign()
+		digits()
+	}
+
+	if start == s.pos {
+		return 0, errors.New(fmt.Sprintf("expected a number at %d", start))
+	}
+
+	return strconv.ParseFloat(s.str[start:s.pos], 64)
+}
+
+// arc flags are a single 0 or 1, and need not be separated from what follows
+func (s *pathScanner) flag() (float64, error) {
+
+	s.skipSeparators()
+
+	if s.done() || (s.str[s.pos] != '0' && s.str[s.pos] != '1') {
+		return 0, errors.New(fmt.Sprintf("expected an arc flag at %d", s.pos))
+	}
+
+	s.pos++
+
+	return float64(s.str[s.pos-1] - '0'), nil
+}
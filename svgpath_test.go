@@ -0,0 +1,69 @@
+package parsesvg
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestParsePathData(t *testing.T) {
+
+	commands, err := parsePathData("M10 20 l5,0 h5 v5 z m1.5.5-2 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pathCommand{
+		{Op: 'M', Points: []geo.Point{{X: 10, Y: 20}}},
+		{Op: 'L', Points: []geo.Point{{X: 15, Y: 20}}},
+		{Op: 'L', Points: []geo.Point{{X: 20, Y: 20}}},
+		{Op: 'L', Points: []geo.Point{{X: 20, Y: 25}}},
+		{Op: 'Z'},
+		{Op: 'M', Points: []geo.Point{{X: 11.5, Y: 20.5}}},
+		{Op: 'L', Points: []geo.Point{{X: 9.5, Y: 20.5}}},
+	}
+
+	if !reflect.DeepEqual(want, commands) {
+		t.Errorf("Path wrong\n%v\n%v", want, commands)
+	}
+
+	// a quadratic is an exact cubic
+	commands, err = parsePathData("M0 0 Q 3 3 6 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = []pathCommand{
+		{Op: 'M', Points: []geo.Point{{X: 0, Y: 0}}},
+		{Op: 'C', Points: []geo.Point{{X: 2, Y: 2}, {X: 4, Y: 2}, {X: 6, Y: 0}}},
+	}
+
+	if !reflect.DeepEqual(want, commands) {
+		t.Errorf("Quadratic wrong\n%v\n%v", want, commands)
+	}
+
+	// a half circle, with flags run together, is two quarters
+	commands, err = parsePathData("M0 0 A10 10 0 0120 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commands) != 3 {
+		t.Fatalf("Expected move and two curves, got %v", commands)
+	}
+
+	mid := commands[1].Points[2]
+	end := commands[2].Points[2]
+	if math.Abs(mid.X-10) > 1e-9 || math.Abs(mid.Y+10) > 1e-9 || end != (geo.Point{X: 20, Y: 0}) {
+		t.Errorf("Arc wrong %v", commands)
+	}
+
+	for _, bad := range []string{"M 0 0 X 1", "M 0", "M0 0 A 1 1 0 2 0 1 1"} {
+		_, err = parsePathData(bad)
+		if err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -39,27 +39,40 @@ type Cdefs__svg struct {
 }
 
 type Cnamedview__sodipodi struct {
-	XMLName                                xml.Name `xml:"namedview,omitempty" json:"namedview,omitempty"`
-	Attrbordercolor                        string   `xml:"bordercolor,attr"  json:",omitempty"`
-	Attrborderopacity                      string   `xml:"borderopacity,attr"  json:",omitempty"`
-	AttrInkscapeSpacecurrent_dash_layer    string   `xml:"http://www.inkscape.org/namespaces/inkscape current-layer,attr"  json:",omitempty"`
-	AttrInkscapeSpacecx                    string   `xml:"http://www.inkscape.org/namespaces/inkscape cx,attr"  json:",omitempty"`
-	AttrInkscapeSpacecy                    string   `xml:"http://www.inkscape.org/namespaces/inkscape cy,attr"  json:",omitempty"`
-	AttrInkscapeSpacedocument_dash_units   string   `xml:"http://www.inkscape.org/namespaces/inkscape document-units,attr"  json:",omitempty"`
-	Attrid                                 string   `xml:"id,attr"  json:",omitempty"`
-	Attrpagecolor                          string   `xml:"pagecolor,attr"  json:",omitempty"`
-	AttrInkscapeSpacepageopacity           string   `xml:"http://www.inkscape.org/namespaces/inkscape pageopacity,attr"  json:",omitempty"`
-	AttrInkscapeSpacepageshadow            string   `xml:"http://www.inkscape.org/namespaces/inkscape pageshadow,attr"  json:",omitempty"`
-	Attrshowgrid                           string   `xml:"showgrid,attr"  json:",omitempty"`
-	AttrInkscapeSpacesnap_dash_center      string   `xml:"http://www.inkscape.org/namespaces/inkscape snap-center,attr"  json:",omitempty"`
-	AttrInkscapeSpacesnap_dash_global      string   `xml:"http://www.inkscape.org/namespaces/inkscape snap-global,attr"  json:",omitempty"`
-	AttrInkscapeSpacesnap_dash_page        string   `xml:"http://www.inkscape.org/namespaces/inkscape snap-page,attr"  json:",omitempty"`
-	AttrInkscapeSpacewindow_dash_height    string   `xml:"http://www.inkscape.org/namespaces/inkscape window-height,attr"  json:",omitempty"`
-	AttrInkscapeSpacewindow_dash_maximized string   `xml:"http://www.inkscape.org/namespaces/inkscape window-maximized,attr"  json:",omitempty"`
-	AttrInkscapeSpacewindow_dash_width     string   `xml:"http://www.inkscape.org/namespaces/inkscape window-width,attr"  json:",omitempty"`
-	AttrInkscapeSpacewindow_dash_x         string   `xml:"http://www.inkscape.org/namespaces/inkscape window-x,attr"  json:",omitempty"`
-	AttrInkscapeSpacewindow_dash_y         string   `xml:"http://www.inkscape.org/namespaces/inkscape window-y,attr"  json:",omitempty"`
-	AttrInkscapeSpacezoom                  string   `xml:"http://www.inkscape.org/namespaces/inkscape zoom,attr"  json:",omitempty"`
+	XMLName                                xml.Name           `xml:"namedview,omitempty" json:"namedview,omitempty"`
+	Attrbordercolor                        string             `xml:"bordercolor,attr"  json:",omitempty"`
+	Attrborderopacity                      string             `xml:"borderopacity,attr"  json:",omitempty"`
+	AttrInkscapeSpacecurrent_dash_layer    string             `xml:"http://www.inkscape.org/namespaces/inkscape current-layer,attr"  json:",omitempty"`
+	AttrInkscapeSpacecx                    string             `xml:"http://www.inkscape.org/namespaces/inkscape cx,attr"  json:",omitempty"`
+	AttrInkscapeSpacecy                    string             `xml:"http://www.inkscape.org/namespaces/inkscape cy,attr"  json:",omitempty"`
+	AttrInkscapeSpacedocument_dash_units   string             `xml:"http://www.inkscape.org/namespaces/inkscape document-units,attr"  json:",omitempty"`
+	Attrid                                 string             `xml:"id,attr"  json:",omitempty"`
+	Attrpagecolor                          string             `xml:"pagecolor,attr"  json:",omitempty"`
+	AttrInkscapeSpacepageopacity           string             `xml:"http://www.inkscape.org/namespaces/inkscape pageopacity,attr"  json:",omitempty"`
+	AttrInkscapeSpacepageshadow            string             `xml:"http://www.inkscape.org/namespaces/inkscape pageshadow,attr"  json:",omitempty"`
+	Attrshowgrid                           string             `xml:"showgrid,attr"  json:",omitempty"`
+	AttrInkscapeSpacesnap_dash_center      string             `xml:"http://www.inkscape.org/namespaces/inkscape snap-center,attr"  json:",omitempty"`
+	AttrInkscapeSpacesnap_dash_global      string             `xml:"http://www.inkscape.org/namespaces/inkscape snap-global,attr"  json:",omitempty"`
+	AttrInkscapeSpacesnap_dash_page        string             `xml:"http://www.inkscape.org/namespaces/inkscape snap-page,attr"  json:",omitempty"`
+	AttrInkscapeSpacewindow_dash_height    string             `xml:"http://www.inkscape.org/namespaces/inkscape window-height,attr"  json:",omitempty"`
+	AttrInkscapeSpacewindow_dash_maximized string             `xml:"http://www.inkscape.org/namespaces/inkscape window-maximized,attr"  json:",omitempty"`
+	AttrInkscapeSpacewindow_dash_width     string             `xml:"http://www.inkscape.org/namespaces/inkscape window-width,attr"  json:",omitempty"`
+	AttrInkscapeSpacewindow_dash_x         string             `xml:"http://www.inkscape.org/namespaces/inkscape window-x,attr"  json:",omitempty"`
+	AttrInkscapeSpacewindow_dash_y         string             `xml:"http://www.inkscape.org/namespaces/inkscape window-y,attr"  json:",omitempty"`
+	AttrInkscapeSpacezoom                  string             `xml:"http://www.inkscape.org/namespaces/inkscape zoom,attr"  json:",omitempty"`
+	Cpage__inkscape                        []*Cpage__inkscape `xml:"http://www.inkscape.org/namespaces/inkscape page,omitempty" json:"page,omitempty"`
+}
+
+// Cpage__inkscape is one page of an inkscape 1.2+ multi-page document, in
+// user units
+type Cpage__inkscape struct {
+	XMLName                xml.Name `xml:"page,omitempty" json:"page,omitempty"`
+	Id                     string   `xml:"id,attr"  json:",omitempty"`
+	AttrInkscapeSpacelabel string   `xml:"http://www.inkscape.org/namespaces/inkscape label,attr"  json:",omitempty"`
+	X                      string   `xml:"x,attr"  json:",omitempty"`
+	Y                      string   `xml:"y,attr"  json:",omitempty"`
+	Width                  string   `xml:"width,attr"  json:",omitempty"`
+	Height                 string   `xml:"height,attr"  json:",omitempty"`
 }
 
 type Cmetadata__svg struct {
@@ -97,14 +110,17 @@ type Ctitle__dc struct {
 }
 
 type Cg__svg struct {
-	XMLName                    xml.Name      `xml:"g,omitempty" json:"g,omitempty"`
-	AttrInkscapeSpacegroupmode string        `xml:"http://www.inkscape.org/namespaces/inkscape groupmode,attr"  json:",omitempty"`
-	Attrid                     string        `xml:"id,attr"  json:",omitempty"`
-	AttrInkscapeSpacelabel     string        `xml:"http://www.inkscape.org/namespaces/inkscape label,attr"  json:",omitempty"`
-	Attrstyle                  string        `xml:"style,attr"  json:",omitempty"`
-	Cpath__svg                 []*Cpath__svg `xml:"http://www.w3.org/2000/svg path,omitempty" json:"path,omitempty"`
-	Crect__svg                 []*Crect__svg `xml:"http://www.w3.org/2000/svg rect,omitempty" json:"rect,omitempty"`
-	Transform                  string        `xml:"transform,attr"  json:",omitempty"`
+	XMLName                    xml.Name        `xml:"g,omitempty" json:"g,omitempty"`
+	AttrInkscapeSpacegroupmode string          `xml:"http://www.inkscape.org/namespaces/inkscape groupmode,attr"  json:",omitempty"`
+	Attrid                     string          `xml:"id,attr"  json:",omitempty"`
+	AttrInkscapeSpacelabel     string          `xml:"http://www.inkscape.org/namespaces/inkscape label,attr"  json:",omitempty"`
+	Attrstyle                  string          `xml:"style,attr"  json:",omitempty"`
+	Cg__svg                    []*Cg__svg      `xml:"http://www.w3.org/2000/svg g,omitempty" json:"groups,omitempty"`
+	Ccircle__svg               []*Ccircle__svg `xml:"http://www.w3.org/2000/svg circle,omitempty" json:"circle,omitempty"`
+	Cpath__svg                 []*Cpath__svg   `xml:"http://www.w3.org/2000/svg path,omitempty" json:"path,omitempty"`
+	Crect__svg                 []*Crect__svg   `xml:"http://www.w3.org/2000/svg rect,omitempty" json:"rect,omitempty"`
+	Cimage__svg                []*Cimage__svg  `xml:"http://www.w3.org/2000/svg image,omitempty" json:"image,omitempty"`
+	Transform                  string          `xml:"transform,attr"  json:",omitempty"`
 }
 
 type Cpath__svg struct {
@@ -138,6 +154,33 @@ type Crect__svg struct {
 	Transform string       `xml:"transform,attr"  json:",omitempty"`
 }
 
+type Cimage__svg struct {
+	XMLName   xml.Name     `xml:"image,omitempty" json:"image,omitempty"`
+	Height    string       `xml:"height,attr"  json:",omitempty"`
+	Id        string       `xml:"id,attr"  json:",omitempty"`
+	Attrstyle string       `xml:"style,attr"  json:",omitempty"`
+	Width     string       `xml:"width,attr"  json:",omitempty"`
+	Rx        string       `xml:"x,attr"  json:",omitempty"`
+	Ry        string       `xml:"y,attr"  json:",omitempty"`
+	Href      string       `xml:"href,attr"  json:",omitempty"`
+	XlinkHref string       `xml:"http://www.w3.org/1999/xlink href,attr"  json:",omitempty"`
+	Desc      *Cdesc__svg  `xml:"http://www.w3.org/2000/svg desc,omitempty" json:"desc,omitempty"`
+	Title     *Ctitle__svg `xml:"http://www.w3.org/2000/svg title,omitempty" json:"title,omitempty"`
+	Transform string       `xml:"transform,attr"  json:",omitempty"`
+}
+
+type Ccircle__svg struct {
+	XMLName   xml.Name     `xml:"circle,omitempty" json:"circle,omitempty"`
+	Cx        string       `xml:"cx,attr"  json:",omitempty"`
+	Cy        string       `xml:"cy,attr"  json:",omitempty"`
+	Id        string       `xml:"id,attr"  json:",omitempty"`
+	R         string       `xml:"r,attr"  json:",omitempty"`
+	Attrstyle string       `xml:"style,attr"  json:",omitempty"`
+	Desc      *Cdesc__svg  `xml:"http://www.w3.org/2000/svg desc,omitempty" json:"desc,omitempty"`
+	Title     *Ctitle__svg `xml:"http://www.w3.org/2000/svg title,omitempty" json:"title,omitempty"`
+	Transform string       `xml:"transform,attr"  json:",omitempty"`
+}
+
 type Cdesc__svg struct {
 	XMLName xml.Name `xml:"desc,omitempty" json:"desc,omitempty"`
 	Attrid  string   `xml:"id,attr"  json:",omitempty"`
@@ -0,0 +1,85 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/timdrysdale/geo"
+)
+
+// TemplateContext is what the text of textprefills, and the prefills of
+// textfields, are executed against, if they hold a Go template, e.g.
+// {{.Exam}} – page {{.PageNumber}}. It has all of the SpreadContents,
+// including the PageData and Vars, and the date the page was rendered.
+// Only text from the design is a template, never what it is filled with.
+type TemplateContext struct {
+	SpreadContents
+	Date time.Time
+}
+
+func newTemplateContext(contents SpreadContents) TemplateContext {
+	return TemplateContext{SpreadContents: contents, Date: time.Now()}
+}
+
+// expandTemplate only treats text with {{ in it as a template, so that
+// plain text, with any braces it has, is left as it always was. Using a
+// Var that isn't set is an error, rather than a blank on the page.
+func expandTemplate(id, text string, context TemplateContext) (string, error) {
+
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	t, err := template.New(id).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("%s has a template that can't be read: %v", id, err))
+	}
+
+	var expanded strings.Builder
+
+	err = t.Execute(&expanded, context)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("%s has a template that can't be filled in: %v", id, err))
+	}
+
+	return expanded.String(), nil
+}
+
+// addLadderText adds the ladder's textfields and textprefills to the
+// spread, with the ladder's top left corner at corner, filling in their
+// templates. This happens before the prefill overrides and placeholders
+// are added, so what a marker typed, e.g. in a previous field, is never
+// run as a template.
+func (s *Spread) addLadderText(ladder *Ladder, corner geo.Point, context TemplateContext) error {
+
+	for _, tf := range ladder.TextFields {
+
+		prefill, err := expandTemplate(tf.ID, tf.Prefill, context)
+		if err != nil {
+			return err
+		}
+
+		//shift the text field and add it to the list
+		//let engine take care of mangling name to suit page
+		tf.Prefill = prefill
+		tf.Rect.Corner = TranslatePosition(corner, tf.Rect.Corner)
+		s.TextFields = append(s.TextFields, tf)
+	}
+
+	for _, tp := range ladder.TextPrefills {
+
+		text, err := expandTemplate(tp.ID, tp.Text.Text, context)
+		if err != nil {
+			return err
+		}
+
+		tp.Text.Text = text
+		tp.Rect.Corner = TranslatePosition(corner, tp.Rect.Corner)
+		s.TextPrefills = append(s.TextPrefills, tp)
+	}
+
+	return nil
+}
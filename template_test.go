@@ -0,0 +1,102 @@
+package parsesvg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestExpandTemplate(t *testing.T) {
+
+	context := TemplateContext{
+		SpreadContents: SpreadContents{Exam: "MATH101", PageNumber: 3, Vars: map[string]string{"room": "JCMB 5327"}},
+		Date:           time.Date(2020, 5, 4, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"{{.Exam}} – page {{.PageNumber}}", "MATH101 – page 3"},
+		{"Room {{.Vars.room}}", "Room JCMB 5327"},
+		{`{{.Date.Format "2 Jan 2006"}}`, "4 May 2020"},
+		{"plain text, with a } brace", "plain text, with a } brace"},
+	}
+
+	for _, test := range tests {
+		got, err := expandTemplate("tp", test.text, context)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.text, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %s, expected %s", test.text, got, test.want)
+		}
+	}
+
+	for _, text := range []string{"{{.Exam", "{{.Weather}}", "{{.Vars.desk}}"} {
+		_, err := expandTemplate("tp", text, context)
+		if err == nil {
+			t.Errorf("%s: expected an error", text)
+		}
+	}
+}
+
+func TestTemplatesOnlyFromDesign(t *testing.T) {
+
+	box := geo.Rect{Corner: geo.Point{X: 10, Y: 100}, Dim: geo.Dim{Width: 20, Height: 10}}
+
+	ladder := &Ladder{
+		TextFields:   []TextField{TextField{ID: "room", Rect: box, Prefill: "{{.Vars.room}}"}},
+		TextPrefills: []TextPrefill{TextPrefill{ID: "title", Rect: box, Text: Paragraph{Text: "{{.Exam}} exam"}}},
+		Placeholders: []TextField{
+			TextField{ID: "script-info-student", Rect: box},
+			TextField{ID: "prev-fields", Rect: box},
+		},
+	}
+
+	// what markers and candidates typed is never a template
+	contents := SpreadContents{
+		Exam:           "MATH101",
+		Candidate:      "{{.Vars.room}}",
+		PreviousFields: map[string]string{"comment": "see {{ here", "mark": "{{.Exam}}"},
+		Vars:           map[string]string{"room": "JCMB"},
+	}
+
+	spread := &Spread{Name: "mark"}
+
+	err := spread.addLadderText(ladder, geo.Point{X: 5, Y: 0}, newTemplateContext(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = spread.fillPlaceholders("svg-mark-header", ladder, geo.Point{X: 5, Y: 0}, contents, nil, templateSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefills := map[string]string{}
+	for _, tf := range spread.TextFields {
+		prefills[tf.ID] = tf.Prefill
+	}
+	for _, tp := range spread.TextPrefills {
+		prefills[tp.ID] = tp.Text.Text
+	}
+
+	want := map[string]string{
+		"room":                "JCMB",
+		"title":               "MATH101 exam",
+		"script-info-student": "{{.Vars.room}}",
+		"prevfield-comment":   "see {{ here",
+		"prevfield-mark":      "{{.Exam}}",
+	}
+
+	if !reflect.DeepEqual(prefills, want) {
+		t.Errorf("Prefills wrong\n%v\n%v", want, prefills)
+	}
+
+	if spread.TextFields[0].Rect.Corner.X != 15 {
+		t.Errorf("Textfield not moved to the ladder's corner %v", spread.TextFields[0].Rect)
+	}
+}
@@ -0,0 +1,205 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+)
+
+// Matrix is an SVG affine transform, stored in the same order as the
+// arguments of matrix(a,b,c,d,e,f), so that a point maps as
+//
+//	x' = a*x + c*y + e
+//	y' = b*x + d*y + f
+type Matrix [6]float64
+
+// Identity is the transform that leaves points where they are
+var Identity = Matrix{1, 0, 0, 1, 0, 0}
+
+var transformFunction = regexp.MustCompile(`^([a-zA-Z]+)\s*\(([^)]*)\)`)
+var transformNumber = regexp.MustCompile(`[+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?`)
+
+// ParseTransform reads an SVG transform list, e.g.
+// "translate(10,20) rotate(45 5 5) scale(2)", into a single Matrix.
+// Transforms are separated by whitespace and/or commas, and are applied
+// to points from right to left, as per the SVG spec. An empty string
+// gives the Identity.
+func ParseTransform(transform string) (Matrix, error) {
+
+	m := Identity
+
+	rest := strings.TrimSpace(transform)
+
+	for len(rest) > 0 {
+
+		match := transformFunction.FindStringSubmatch(rest)
+		if match == nil {
+			return Identity, errors.New(fmt.Sprintf("couldn't parse transform %q at %q", transform, rest))
+		}
+
+		args, err := parseTransformArgs(match[2])
+		if err != nil {
+			return Identity, errors.New(fmt.Sprintf("couldn't parse transform %q: %v", transform, err))
+		}
+
+		t, err := transformFromArgs(match[1], args)
+		if err != nil {
+			return Identity, errors.New(fmt.Sprintf("couldn't parse transform %q: %v", transform, err))
+		}
+
+		m = m.Multiply(t)
+
+		rest = strings.TrimLeft(rest[len(match[0]):], " \t\r\n,")
+	}
+
+	return m, nil
+}
+
+// split the argument list on commas and/or whitespace, noting that
+// SVG permits "1-2" to mean 1,-2 so we can't just split on separators
+func parseTransformArgs(list string) ([]float64, error) {
+
+	var args []float64
+
+	last := 0
+	for _, loc := range transformNumber.FindAllStringIndex(list, -1) {
+
+		if sep := list[last:loc[0]]; strings.Trim(sep, " \t\r\n,") != "" {
+			return nil, errors.New(fmt.Sprintf("unexpected %q in argument list %q", sep, list))
+		}
+
+		value, err := strconv.ParseFloat(list[loc[0]:loc[1]], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, value)
+		last = loc[1]
+	}
+
+	if tail := list[last:]; strings.Trim(tail, " \t\r\n,") != "" {
+		return nil, errors.New(fmt.Sprintf("unexpected %q in argument list %q", tail, list))
+	}
+
+	return args, nil
+}
+
+func transformFromArgs(name string, args []float64) (Matrix, error) {
+
+	switch {
+
+	case name == "matrix" && len(args) == 6:
+		return Matrix{args[0], args[1], args[2], args[3], args[4], args[5]}, nil
+
+	case name == geo.Translate && len(args) == 1:
+		return Matrix{1, 0, 0, 1, args[0], 0}, nil
+
+	case name == geo.Translate && len(args) == 2:
+		return Matrix{1, 0, 0, 1, args[0], args[1]}, nil
+
+	case name == "scale" && len(args) == 1:
+		return Matrix{args[0], 0, 0, args[0], 0, 0}, nil
+
+	case name == "scale" && len(args) == 2:
+		return Matrix{args[0], 0, 0, args[1], 0, 0}, nil
+
+	case name == "rotate" && len(args) == 1:
+		return rotation(args[0]), nil
+
+	case name == "rotate" && len(args) == 3:
+		// rotate about (cx,cy) = translate(cx,cy) rotate(a) translate(-cx,-cy)
+		there := Matrix{1, 0, 0, 1, args[1], args[2]}
+		back := Matrix{1, 0, 0, 1, -args[1], -args[2]}
+		return there.Multiply(rotation(args[0])).Multiply(back), nil
+
+	case name == "skewX" && len(args) == 1:
+		return Matrix{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}, nil
+
+	case name == "skewY" && len(args) == 1:
+		return Matrix{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}, nil
+	}
+
+	return Identity, errors.New(fmt.Sprintf("%s does not take %d argument(s)", name, len(args)))
+}
+
+// angle is in degrees, as in the SVG
+func rotation(angle float64) Matrix {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return Matrix{cos, sin, -sin, cos, 0, 0}
+}
+
+// Multiply returns the transform that applies n first, then m. This is
+// the order needed for nesting, i.e. parent.Multiply(child)
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+// Apply maps a point through the transform
+func (m Matrix) Apply(p geo.Point) geo.Point {
+	return geo.Point{
+		X: m[0]*p.X + m[2]*p.Y + m[4],
+		Y: m[1]*p.X + m[3]*p.Y + m[5],
+	}
+}
+
+// ApplyRect maps a rect through the transform. Acroforms are always
+// axis-aligned, so if the transform rotates or skews the rect, we return
+// the bounding box of the transformed corners instead.
+func (m Matrix) ApplyRect(r geo.Rect) geo.Rect {
+
+	if m[1] == 0 && m[2] == 0 {
+		// axis-aligned, so scale the dims directly to avoid rounding
+		// errors creeping in from differencing the corners
+		corner := m.Apply(r.Corner)
+		width := m[0] * r.Dim.Width
+		height := m[3] * r.Dim.Height
+
+		if width < 0 {
+			width = -width
+			corner.X = corner.X - width
+		}
+		if height < 0 {
+			height = -height
+			corner.Y = corner.Y - height
+		}
+
+		return geo.Rect{
+			Corner: corner,
+			Dim:    geo.Dim{Width: width, Height: height, DynamicWidth: r.Dim.DynamicWidth},
+		}
+	}
+
+	corners := []geo.Point{
+		m.Apply(r.Corner),
+		m.Apply(geo.Point{X: r.Corner.X + r.Dim.Width, Y: r.Corner.Y}),
+		m.Apply(geo.Point{X: r.Corner.X, Y: r.Corner.Y + r.Dim.Height}),
+		m.Apply(geo.Point{X: r.Corner.X + r.Dim.Width, Y: r.Corner.Y + r.Dim.Height}),
+	}
+
+	min := corners[0]
+	max := corners[0]
+
+	for _, c := range corners[1:] {
+		min.X = math.Min(min.X, c.X)
+		min.Y = math.Min(min.Y, c.Y)
+		max.X = math.Max(max.X, c.X)
+		max.Y = math.Max(max.Y, c.Y)
+	}
+
+	return geo.Rect{
+		Corner: min,
+		Dim:    geo.Dim{Width: max.X - min.X, Height: max.Y - min.Y, DynamicWidth: r.Dim.DynamicWidth},
+	}
+}
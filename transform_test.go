@@ -0,0 +1,129 @@
+package parsesvg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/timdrysdale/geo"
+)
+
+func matricesEqual(a, b Matrix) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseTransform(t *testing.T) {
+
+	tests := []struct {
+		transform string
+		want      Matrix
+	}{
+		{"", Identity},
+		{"translate(0,-247)", Matrix{1, 0, 0, 1, 0, -247}},
+		{"translate(12)", Matrix{1, 0, 0, 1, 12, 0}},
+		{"translate(1e1 -2.5)", Matrix{1, 0, 0, 1, 10, -2.5}},
+		{"translate(1-2)", Matrix{1, 0, 0, 1, 1, -2}},
+		{"scale(2)", Matrix{2, 0, 0, 2, 0, 0}},
+		{"scale(2,3)", Matrix{2, 0, 0, 3, 0, 0}},
+		{"matrix(0.83470821,0,0,0.82232123,-166.30073,-13.565732)", Matrix{0.83470821, 0, 0, 0.82232123, -166.30073, -13.565732}},
+		{"rotate(90)", Matrix{0, 1, -1, 0, 0, 0}},
+		{"rotate(90 10 10)", Matrix{0, 1, -1, 0, 20, 0}},
+		{"skewX(45)", Matrix{1, 0, 1, 1, 0, 0}},
+		{"skewY(45)", Matrix{1, 1, 0, 1, 0, 0}},
+		{"translate(10,20) scale(2)", Matrix{2, 0, 0, 2, 10, 20}},
+		{"translate(10,20),scale(2)", Matrix{2, 0, 0, 2, 10, 20}},
+		{"scale(2) translate(10,20)", Matrix{2, 0, 0, 2, 20, 40}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTransform(test.transform)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.transform, err)
+		}
+		if !matricesEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.transform, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"translate", "translate(1,2", "scale(1,2,3)", "wobble(1)", "translate(1,two)", "translate(1,2) ???"} {
+		if _, err := ParseTransform(bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestApplyRect(t *testing.T) {
+
+	r := geo.Rect{Corner: geo.Point{X: 10, Y: 20}, Dim: geo.Dim{Width: 30, Height: 40}}
+
+	flip := Matrix{1, 0, 0, -1, 0, 100}
+	want := geo.Rect{Corner: geo.Point{X: 10, Y: 40}, Dim: geo.Dim{Width: 30, Height: 40}}
+	if got := flip.ApplyRect(r); got != want {
+		t.Errorf("flip: got %v, want %v", got, want)
+	}
+
+	rotate, _ := ParseTransform("rotate(90)")
+	want = geo.Rect{Corner: geo.Point{X: -60, Y: 10}, Dim: geo.Dim{Width: 40, Height: 30}}
+	got := rotate.ApplyRect(r)
+	if math.Abs(got.Corner.X-want.Corner.X) > 1e-9 ||
+		math.Abs(got.Corner.Y-want.Corner.Y) > 1e-9 ||
+		math.Abs(got.Dim.Width-want.Dim.Width) > 1e-9 ||
+		math.Abs(got.Dim.Height-want.Dim.Height) > 1e-9 {
+		t.Errorf("rotate: got %v, want %v", got, want)
+	}
+}
+
+var transformedLadderSVG = inkscapeSVG(ptPage, `
+  <g
+     inkscape:label="anchors"
+     inkscape:groupmode="layer"
+     id="layer1"
+     transform="matrix(2,0,0,2,-10,-10)">
+    <path
+       id="path1"
+       sodipodi:type="arc"
+       sodipodi:cx="5"
+       sodipodi:cy="5"
+       sodipodi:rx="1"
+       sodipodi:ry="1"
+       d="m 0,0">
+      <title>ref-anchor</title>
+    </path>
+  </g>
+  <g
+     inkscape:label="textfields"
+     inkscape:groupmode="layer"
+     id="layer2"
+     transform="translate(10,0)">
+    <rect
+       id="rect1"
+       transform="scale(2,0.5)"
+       x="5"
+       y="40"
+       width="10"
+       height="20">
+      <title>scaled</title>
+    </rect>
+  </g>
+`)
+
+func TestDefineLadderFromSVGTransforms(t *testing.T) {
+
+	ladder, err := DefineLadderFromSVG([]byte(transformedLadderSVG))
+	if err != nil {
+		t.Fatalf("Error defining ladder %v", err)
+	}
+
+	if ladder.Anchor != (geo.Point{X: 0, Y: 0}) {
+		t.Errorf("anchor: got %v", ladder.Anchor)
+	}
+
+	want := geo.Rect{Corner: geo.Point{X: 20, Y: 20}, Dim: geo.Dim{Width: 20, Height: 10}}
+	if len(ladder.TextFields) != 1 || ladder.TextFields[0].Rect != want {
+		t.Errorf("textfield: got %v, want %v", ladder.TextFields, want)
+	}
+}
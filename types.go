@@ -1,6 +1,8 @@
 package parsesvg
 
 import (
+	"io/fs"
+
 	"github.com/timdrysdale/geo"
 	"github.com/timdrysdale/pdfcomment"
 	"github.com/timdrysdale/pdfpagedata"
@@ -22,6 +24,18 @@ type SpreadContents struct {
 	PageData          pdfpagedata.PageData
 	Prefills          DocPrefills
 	PreviousFields	  map[string]string
+	// Templates, if set, is where the layout, ladders and chrome images are
+	// read from, with SvgLayoutPath inside it, and everything else relative
+	// to the layout's directory. If nil, they are read from the working
+	// directory as before. Previous and prefill images are always read
+	// from the working directory, because they are not part of the design.
+	Templates fs.FS
+	// VectorChrome draws the chrome layer of each ladder's svg, instead
+	// of its exported png, so it stays sharp when zoomed
+	VectorChrome bool
+	// Vars are extra values for templates in prefills and textfields,
+	// used as {{.Vars.name}}
+	Vars map[string]string
 }
 
 // Structure for the optional reading a csv of parts and marks
@@ -30,21 +44,108 @@ type PaperStructure struct {
 	Marks      int     `csv:"marks"`
 }
 
+// layers that are not (yet) in geo
+const (
+	CheckBoxesLayer   = "checkboxes"
+	ComboBoxesLayer   = "comboboxes"
+	DropDownsLayer    = "dropdowns"
+	RadioButtonsLayer = "radiobuttons"
+	ChromeLayer       = "chrome"
+)
+
 type PagePrefills map[string]string
 type DocPrefills map[int]PagePrefills
 
+// TextField is an editable acroform text field. Its Description field is
+// either the plain text to prefill it with, or a JSON object of options,
+// e.g. {"prefill":"0","maxLen":2,"align":"right","tooltip":"Mark for Q1"}
 type TextField struct {
-	Rect        geo.Rect
-	ID          string
-	Prefill     string
-	TabSequence int64
+	Rect        geo.Rect         `json:"rect"`
+	ID          string           `json:"id"`
+	Prefill     string           `json:"prefill"`
+	TabSequence int64            `json:"tabSequence"`
+	Options     TextFieldOptions `json:"options"`
+}
+
+type TextFieldOptions struct {
+	Prefill   string   `json:"prefill"`
+	MaxLen    int      `json:"maxLen"`
+	Multiline bool     `json:"multiline"`
+	Comb      bool     `json:"comb"`
+	Font      string   `json:"font"`
+	FontSize  float64  `json:"fontSize"`
+	Align     string   `json:"align"`
+	ReadOnly  bool     `json:"readOnly"`
+	Required  bool     `json:"required"`
+	Tooltip   string   `json:"tooltip"`
+	Numeric   bool     `json:"numeric"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	Step      float64  `json:"step"`
+	Sum       []string `json:"sum"`
+}
+
+// CheckBox is a tickable acroform field. Its options are read from a JSON
+// object in the Description field, e.g. {"checked":true,"onValue":"Seen"}
+type CheckBox struct {
+	Rect        geo.Rect        `json:"rect"`
+	ID          string          `json:"id"`
+	Properties  string          `json:"properties"`
+	TabSequence int64           `json:"tabSequence"`
+	Options     CheckBoxOptions `json:"options"`
+}
+
+// The off state is always exported as "Off", because that is the only
+// name the PDF spec allows for it, so only the on value can be changed
+type CheckBoxOptions struct {
+	Checked bool   `json:"checked"`
+	OnValue string `json:"onValue"`
+}
+
+// DropDown is a constrained choice acroform field. Its options are read
+// from a JSON object in the Description field, e.g.
+// {"choices":["Agree","Disagree"],"default":"Agree"}
+type DropDown struct {
+	Rect        geo.Rect      `json:"rect"`
+	ID          string        `json:"id"`
+	Properties  string        `json:"properties"`
+	TabSequence int64         `json:"tabSequence"`
+	Options     ChoiceOptions `json:"options"`
+}
+
+// ComboBox is a DropDown that also accepts typed-in values, so its
+// default does not have to be one of the choices
+type ComboBox DropDown
+
+type ChoiceOptions struct {
+	Choices []string `json:"choices"`
+	Default string   `json:"default"`
+}
+
+// RadioGroup is a set of buttons of which only one can be on. The buttons
+// are drawn on the radiobuttons layer, titled <group>-radio-<value>, e.g.
+// q1-radio-3, and a button can be on by default if its description is
+// {"checked":true}
+type RadioGroup struct {
+	ID          string        `json:"id"`
+	Default     string        `json:"default"`
+	TabSequence int64         `json:"tabSequence"`
+	Buttons     []RadioButton `json:"buttons"`
+}
+
+// RadioButton is one choice in a RadioGroup, exporting its Value when on
+type RadioButton struct {
+	Rect        geo.Rect `json:"rect"`
+	ID          string   `json:"id"`
+	Value       string   `json:"value"`
+	TabSequence int64    `json:"tabSequence"`
 }
 
 type TextPrefill struct {
-	Rect       geo.Rect
-	ID         string
-	Properties string
-	Text       Paragraph
+	Rect       geo.Rect  `json:"rect"`
+	ID         string    `json:"id"`
+	Properties string    `json:"properties"`
+	Text       Paragraph `json:"text"`
 }
 
 // we read the properties from a JSON object in the Description field
@@ -56,7 +157,7 @@ type Paragraph struct {
 	TextSize            float64   `json:"textSize"`
 	LineHeight          float64   `json:"lineHeight"`
 	Alignment           string    `json:"alignment"`
-	EnableWrap          bool      `json:"enableWrap"`
+	EnableWrap          *bool     `json:"enableWrap"` // wraps unless set false, as before it was read
 	WrapWidth           float64   `json:"wrapWidth"`
 	Angle               float64   `json:"angle"`
 	AbsolutePositioning bool      `json:"absolutePositioning"`
@@ -67,39 +168,51 @@ type Paragraph struct {
 }
 
 type Ladder struct {
-	Anchor       geo.Point
-	Dim          geo.Dim
-	ID           string
-	TextFields   []TextField
-	TextPrefills []TextPrefill
-	Placeholders []TextField
+	Anchor       geo.Point     `json:"anchor"`
+	Dim          geo.Dim       `json:"dim"`
+	ID           string        `json:"id"`
+	TextFields   []TextField   `json:"textFields"`
+	TextPrefills []TextPrefill `json:"textPrefills"`
+	Placeholders []TextField   `json:"placeholders"`
+	CheckBoxes   []CheckBox    `json:"checkBoxes"`
+	DropDowns    []DropDown    `json:"dropDowns"`
+	ComboBoxes   []ComboBox    `json:"comboBoxes"`
+	RadioGroups  []RadioGroup  `json:"radioGroups"`
+	Images       []ImageInsert `json:"images"`
 }
 
 type Layout struct {
-	Anchor    geo.Point            `json:"anchor"`
-	Dim       geo.Dim              `json:"dim"`
-	ID        string               `json:"id"`
-	Anchors   map[string]geo.Point `json:"anchors"`
-	PageDims  map[string]geo.Dim   `json:"pageDims"`
-	Filenames map[string]string    `json:"filenames"`
-	ImageDims map[string]geo.Dim   `json:"ImageDims"`
+	Anchor    geo.Point              `json:"anchor"`
+	Dim       geo.Dim                `json:"dim"`
+	ID        string                 `json:"id"`
+	Anchors   map[string]geo.Point   `json:"anchors"`
+	PageDims  map[string]geo.Dim     `json:"pageDims"`
+	Filenames map[string]string      `json:"filenames"`
+	ImageDims map[string]geo.Dim     `json:"ImageDims"`
+	Images    map[string]ImageInsert `json:"images"`
 }
 
-//TODO move this to types.go; add json tags
 type Spread struct {
-	Name         string
-	Dim          geo.Dim
-	ExtraWidth   float64
-	Images       []ImageInsert
-	Ladders      []Ladder
-	TextFields   []TextField
-	TextPrefills []TextPrefill
+	Name         string        `json:"name"`
+	Dim          geo.Dim       `json:"dim"`
+	ExtraWidth   float64       `json:"extraWidth"`
+	Images       []ImageInsert `json:"images"`
+	Ladders      []Ladder      `json:"ladders"`
+	TextFields   []TextField   `json:"textFields"`
+	TextPrefills []TextPrefill `json:"textPrefills"`
+	CheckBoxes   []CheckBox    `json:"checkBoxes"`
+	DropDowns    []DropDown    `json:"dropDowns"`
+	ComboBoxes   []ComboBox    `json:"comboBoxes"`
+	RadioGroups  []RadioGroup  `json:"radioGroups"`
 }
 
 type ImageInsert struct {
-	Filename string
-	Corner   geo.Point
-	Dim      geo.Dim
+	Filename string        `json:"filename"`
+	Corner   geo.Point     `json:"corner"`
+	Dim      geo.Dim       `json:"dim"`
+	Data     []byte        `json:"data,omitempty"`   // contents of an embedded image, used instead of Filename
+	FS       fs.FS         `json:"-"`                // where to find Filename; nil is the working directory
+	Vector   *VectorChrome `json:"vector,omitempty"` // drawn instead of the image, if set
 }
 
 // how to understand dynamic width
@@ -130,3 +243,4 @@ func (s *Spread) GetWidth() float64 {
 //TimesBold
 //TimesItalic
 //TimesBoldItalic
+//and any TrueType font registered with RegisterFont, by the name it was given
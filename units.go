@@ -0,0 +1,123 @@
+package parsesvg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+)
+
+// Length is an SVG/CSS length, e.g. 210mm, 12.5pt, 300 or 50%. A length
+// with no unit is in user units.
+type Length struct {
+	Value float64
+	Unit  string
+}
+
+// pointsPerUnit are the absolute units, in points. User units are taken to
+// be px, as per CSS, which is also what inkscape does.
+var pointsPerUnit = map[string]float64{
+	"":   geo.PPPX,
+	"px": geo.PPPX,
+	"pt": 1,
+	"pc": 12,
+	"in": geo.PPIN,
+	"cm": 10 * geo.PPMM,
+	"mm": geo.PPMM,
+	"q":  geo.PPMM / 4,
+}
+
+var lengthPattern = regexp.MustCompile(`^([+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?)\s*([a-zA-Z]*|%)$`)
+
+// ParseLength reads a length, returning an error rather than guessing if
+// the number or the unit can't be understood
+func ParseLength(str string) (Length, error) {
+
+	trimmed := strings.TrimSpace(str)
+
+	if trimmed == "" {
+		return Length{}, errors.New("empty length")
+	}
+
+	match := lengthPattern.FindStringSubmatch(trimmed)
+	if match == nil {
+		return Length{}, errors.New(fmt.Sprintf("couldn't parse %q as a number with optional units", str))
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return Length{}, errors.New(fmt.Sprintf("couldn't parse %q as a number: %v", match[1], err))
+	}
+
+	unit := strings.ToLower(match[2])
+
+	if _, ok := pointsPerUnit[unit]; !ok && unit != "%" {
+		return Length{}, errors.New(fmt.Sprintf("didn't understand the units %s in %q", match[2], str))
+	}
+
+	return Length{Value: value, Unit: unit}, nil
+}
+
+// PointsPerUnit is the size of one of the length's units, in points.
+// Percentages don't have a fixed size, so give an error.
+func (l Length) PointsPerUnit() (float64, error) {
+
+	pp, ok := pointsPerUnit[l.Unit]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("can't convert %g%s to points without knowing what it is relative to", l.Value, l.Unit))
+	}
+
+	return pp, nil
+}
+
+// Points is the length in points
+func (l Length) Points() (float64, error) {
+
+	pp, err := l.PointsPerUnit()
+	if err != nil {
+		return 0, err
+	}
+
+	return l.Value * pp, nil
+}
+
+// UserUnits is the length in user units, which is how element positions
+// and sizes are handled until the document scale is applied
+func (l Length) UserUnits() (float64, error) {
+
+	if l.Unit == "" || l.Unit == "px" {
+		return l.Value, nil
+	}
+
+	pp, err := l.PointsPerUnit()
+	if err != nil {
+		return 0, err
+	}
+
+	return l.Value * pp / geo.PPPX, nil
+}
+
+// parseUserUnits reads an element's attribute, e.g. the width of a rect,
+// into user units. Coordinates default to zero when absent, as per SVG,
+// but sizes must be given.
+func parseUserUnits(id, attribute, str string, coordinate bool) (float64, error) {
+
+	if coordinate && strings.TrimSpace(str) == "" {
+		return 0, nil
+	}
+
+	length, err := ParseLength(str)
+	if err != nil {
+		return 0, attributeError(id, attribute, str, err)
+	}
+
+	value, err := length.UserUnits()
+	if err != nil {
+		return 0, attributeError(id, attribute, str, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,82 @@
+package parsesvg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestParseLength(t *testing.T) {
+
+	tests := []struct {
+		str    string
+		points float64
+	}{
+		{"12pt", 12},
+		{" 12 pt ", 12},
+		{"1pc", 12},
+		{"1in", geo.PPIN},
+		{"210mm", 210 * geo.PPMM},
+		{"21cm", 210 * geo.PPMM},
+		{"4Q", geo.PPMM},
+		{"100px", 100 * geo.PPPX},
+		{"100", 100 * geo.PPPX},
+		{"1e2", 100 * geo.PPPX},
+		{".5in", 0.5 * geo.PPIN},
+		{"-3pt", -3},
+	}
+
+	for _, test := range tests {
+		length, err := ParseLength(test.str)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.str, err)
+			continue
+		}
+		points, err := length.Points()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.str, err)
+		}
+		if math.Abs(points-test.points) > 1e-9 {
+			t.Errorf("%s: got %f, want %f", test.str, points, test.points)
+		}
+	}
+
+	for _, bad := range []string{"", "m", "mm", "12ft", "twelve", "12 pt pt", "1.2.3mm"} {
+		if _, err := ParseLength(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+
+	percent, err := ParseLength("50%")
+	if err != nil || percent != (Length{Value: 50, Unit: "%"}) {
+		t.Errorf("50%%: got %v %v", percent, err)
+	}
+
+	if _, err := percent.Points(); err == nil {
+		t.Errorf("50%%: expected an error converting to points")
+	}
+}
+
+func TestParseUserUnits(t *testing.T) {
+
+	x, err := parseUserUnits("rect1", "x", "", true)
+	if err != nil || x != 0 {
+		t.Errorf("missing coordinate should be zero, got %f %v", x, err)
+	}
+
+	_, err = parseUserUnits("rect1", "width", "", false)
+	if err == nil {
+		t.Errorf("missing width should be an error")
+	}
+
+	w, err := parseUserUnits("rect1", "width", "3pt", false)
+	if err != nil || math.Abs(w-4) > 1e-9 {
+		t.Errorf("3pt should be 4 user units, got %f %v", w, err)
+	}
+
+	w, err = parseUserUnits("rect1", "width", "24.735001", false)
+	if err != nil || w != 24.735001 {
+		t.Errorf("unitless width should be unchanged, got %f %v", w, err)
+	}
+}
@@ -0,0 +1,367 @@
+package parsesvg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/timdrysdale/geo"
+)
+
+// styles for the generated elements, so the layers can be told apart
+// when the ladder is opened in inkscape
+const (
+	anchorStyle = "fill:#00c041;fill-opacity:0.55;stroke:none"
+	fieldStyle  = "fill:#0000ff;fill-opacity:0.2;stroke:none"
+	anchorSize  = 3 // radius of the reference anchor, in points
+)
+
+// WriteLadderSVG writes a ladder as an inkscape svg, with each element on
+// the layer, and with the title, tab-N id and description, that
+// DefineLadderFromSVG reads it back from. The page is in points, so the
+// ladder reads back exactly, and there is an empty chrome layer to draw on.
+// Vector chrome is not written, so the ladder is only as pretty as the
+// designer then makes it.
+func WriteLadderSVG(w io.Writer, ladder *Ladder) error {
+	return writeLadderSVG(w, ladder, "")
+}
+
+// writeLadderSVG puts the chrome, which is svg markup, on the chrome layer
+func writeLadderSVG(w io.Writer, ladder *Ladder, chrome string) error {
+
+	if ladder == nil {
+		return errors.New("nil pointer to ladder")
+	}
+
+	s := &svgWriter{}
+
+	s.header(ladder.ID, ladder.Dim)
+
+	s.startLayer(ChromeLayer)
+	s.printf("%s", chrome)
+	s.endLayer()
+
+	if len(ladder.Images) > 0 {
+		s.startLayer(geo.ImagesLayer)
+		for i, im := range ladder.Images {
+			s.image(fmt.Sprintf("image-%d", i+1), im)
+		}
+		s.endLayer()
+	}
+
+	if len(ladder.TextPrefills) > 0 {
+		s.startLayer(geo.TextPrefillsLayer)
+		for i, tp := range ladder.TextPrefills {
+			desc := tp.Properties
+			if desc == "" {
+				var err error
+				desc, err = descJSON(tp.Text)
+				if err != nil {
+					return err
+				}
+			}
+			s.rect(elementID("textprefill", i, 0), tp.ID, desc, tp.Rect)
+		}
+		s.endLayer()
+	}
+
+	if len(ladder.Placeholders) > 0 {
+		s.startLayer("placeholders")
+		for i, tf := range ladder.Placeholders {
+			s.rect(elementID("placeholder", i, tf.TabSequence), tf.ID, tf.Prefill, tf.Rect)
+		}
+		s.endLayer()
+	}
+
+	s.startLayer(geo.TextFieldsLayer)
+	for i, tf := range ladder.TextFields {
+		desc, err := textFieldDesc(tf)
+		if err != nil {
+			return err
+		}
+		s.rect(elementID("textfield", i, tf.TabSequence), tf.ID, desc, tf.Rect)
+	}
+	s.endLayer()
+
+	if len(ladder.CheckBoxes) > 0 {
+		s.startLayer(CheckBoxesLayer)
+		for i, cb := range ladder.CheckBoxes {
+			desc := cb.Properties
+			// unchecked, and exporting "Yes", is what no description means
+			if desc == "" && cb.Options != (CheckBoxOptions{OnValue: "Yes"}) {
+				var err error
+				desc, err = descJSON(cb.Options)
+				if err != nil {
+					return err
+				}
+			}
+			s.rect(elementID("checkbox", i, cb.TabSequence), cb.ID, desc, cb.Rect)
+		}
+		s.endLayer()
+	}
+
+	choices := []struct {
+		layer string
+		kind  string
+		dds   []DropDown
+	}{
+		{DropDownsLayer, "dropdown", ladder.DropDowns},
+		{ComboBoxesLayer, "combobox", nil},
+	}
+	for _, cb := range ladder.ComboBoxes {
+		choices[1].dds = append(choices[1].dds, DropDown(cb))
+	}
+
+	for _, c := range choices {
+		if len(c.dds) == 0 {
+			continue
+		}
+		s.startLayer(c.layer)
+		for i, dd := range c.dds {
+			desc := dd.Properties
+			if desc == "" {
+				var err error
+				desc, err = descJSON(dd.Options)
+				if err != nil {
+					return err
+				}
+			}
+			s.rect(elementID(c.kind, i, dd.TabSequence), dd.ID, desc, dd.Rect)
+		}
+		s.endLayer()
+	}
+
+	if len(ladder.RadioGroups) > 0 {
+		s.startLayer(RadioButtonsLayer)
+		n := 0
+		for _, rg := range ladder.RadioGroups {
+			for _, rb := range rg.Buttons {
+				title := rb.ID
+				if title == "" {
+					title = rg.ID + "-radio-" + rb.Value
+				}
+				desc := ""
+				if rg.Default != "" && rb.Value == rg.Default {
+					desc = `{"checked":true}`
+				}
+				s.rect(elementID("radio", n, rb.TabSequence), title, desc, rb.Rect)
+				n++
+			}
+		}
+		s.endLayer()
+	}
+
+	s.startLayer(geo.AnchorsLayer)
+	s.anchor(ladder.Anchor)
+	s.endLayer()
+
+	s.printf("</svg>\n")
+
+	_, err := w.Write(s.buf.Bytes())
+	return err
+}
+
+// textFieldDesc keeps a plain prefill as it is, as a designer would write
+// it, unless there are options, or the prefill would be mistaken for them
+func textFieldDesc(tf TextField) (string, error) {
+
+	if reflect.DeepEqual(tf.Options, TextFieldOptions{}) && !strings.HasPrefix(strings.TrimSpace(tf.Prefill), "{") {
+		return tf.Prefill, nil
+	}
+
+	options := tf.Options
+	options.Prefill = tf.Prefill
+
+	return descJSON(options)
+}
+
+// descJSON writes the options that aren't zero, so that the description
+// only holds what differs from the defaults, and is empty if nothing does
+func descJSON(options interface{}) (string, error) {
+
+	v := reflect.ValueOf(options)
+	t := v.Type()
+
+	fields := make(map[string]interface{})
+
+	for i := 0; i < t.NumField(); i++ {
+		if v.Field(i).IsZero() {
+			continue
+		}
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		fields[name] = v.Field(i).Interface()
+	}
+
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	// keep <, > and & as they are, since they are escaped for the svg anyway
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(fields)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// elementID gives each element a unique id, ending in tab-N if it has a
+// place in the tab order, e.g. textfield-2-tab-3
+func elementID(kind string, index int, tab int64) string {
+
+	id := fmt.Sprintf("%s-%d", kind, index+1)
+
+	if tab > 0 {
+		id = fmt.Sprintf("%s-tab-%d", id, tab)
+	}
+
+	return id
+}
+
+type svgWriter struct {
+	buf bytes.Buffer
+}
+
+func (s *svgWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&s.buf, format, args...)
+}
+
+// header starts an inkscape svg, with the page in points so that it reads
+// back exactly, and the id as the title in the metadata
+func (s *svgWriter) header(id string, dim geo.Dim) {
+
+	s.printf(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<svg
+   xmlns:dc="http://purl.org/dc/elements/1.1/"
+   xmlns:cc="http://creativecommons.org/ns#"
+   xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+   xmlns:svg="http://www.w3.org/2000/svg"
+   xmlns="http://www.w3.org/2000/svg"
+   xmlns:xlink="http://www.w3.org/1999/xlink"
+   xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
+   xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+   width="%spt"
+   height="%spt"
+   viewBox="0 0 %s %s"
+   version="1.1"
+   id="svg">
+  <sodipodi:namedview
+     id="base"
+     inkscape:document-units="pt" />
+  <metadata
+     id="metadata">
+    <rdf:RDF>
+      <cc:Work
+         rdf:about="">
+        <dc:format>image/svg+xml</dc:format>
+        <dc:title>%s</dc:title>
+      </cc:Work>
+    </rdf:RDF>
+  </metadata>
+`, num(dim.Width), num(dim.Height), num(dim.Width), num(dim.Height), escape(id))
+}
+
+func (s *svgWriter) startLayer(label string) {
+	s.printf(`  <g
+     inkscape:groupmode="layer"
+     id="layer-%s"
+     inkscape:label="%s">
+`, label, label)
+}
+
+func (s *svgWriter) endLayer() {
+	s.printf("  </g>\n")
+}
+
+func (s *svgWriter) rect(id, title, desc string, rect geo.Rect) {
+	s.printf(`    <rect
+       id="%s"
+       x="%s"
+       y="%s"
+       width="%s"
+       height="%s"
+       style="%s">
+`, id, num(rect.Corner.X), num(rect.Corner.Y), num(rect.Dim.Width), num(rect.Dim.Height), fieldStyle)
+	s.titleDesc(title, desc)
+	s.printf("    </rect>\n")
+}
+
+func (s *svgWriter) image(id string, im ImageInsert) {
+
+	href := im.Filename
+	if len(im.Data) > 0 {
+		href = "data:" + http.DetectContentType(im.Data) + ";base64," + base64.StdEncoding.EncodeToString(im.Data)
+	}
+
+	s.printf(`    <image
+       id="%s"
+       x="%s"
+       y="%s"
+       width="%s"
+       height="%s"
+       preserveAspectRatio="none"
+       xlink:href="%s" />
+`, id, num(im.Corner.X), num(im.Corner.Y), num(im.Dim.Width), num(im.Dim.Height), attrEscaper.Replace(href))
+}
+
+// anchor draws the reference anchor
+func (s *svgWriter) anchor(p geo.Point) {
+	s.namedAnchor("ref-anchor", geo.AnchorReference, "", p)
+}
+
+// namedAnchor draws an anchor as inkscape draws a circle, so that its
+// centre is in sodipodi:cx and sodipodi:cy
+func (s *svgWriter) namedAnchor(id, title, desc string, p geo.Point) {
+
+	r := num(anchorSize)
+
+	s.printf(`    <path
+       id="%s"
+       sodipodi:type="arc"
+       sodipodi:cx="%s"
+       sodipodi:cy="%s"
+       sodipodi:rx="%s"
+       sodipodi:ry="%s"
+       d="M %s,%s A %s,%s 0 1 1 %s,%s A %s,%s 0 1 1 %s,%s Z"
+       style="%s">
+`, id, num(p.X), num(p.Y), r, r,
+		num(p.X+anchorSize), num(p.Y), r, r, num(p.X-anchorSize), num(p.Y), r, r, num(p.X+anchorSize), num(p.Y),
+		anchorStyle)
+	s.titleDesc(title, desc)
+	s.printf("    </path>\n")
+}
+
+func (s *svgWriter) titleDesc(title, desc string) {
+	if title != "" {
+		s.printf("      <title>%s</title>\n", escape(title))
+	}
+	if desc != "" {
+		s.printf("      <desc>%s</desc>\n", escape(desc))
+	}
+}
+
+// num writes the shortest form that reads back as the same float
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// quotes are left alone in text, so that descriptions are readable JSON
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func escape(str string) string {
+	return textEscaper.Replace(str)
+}
@@ -0,0 +1,119 @@
+package parsesvg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/timdrysdale/geo"
+)
+
+func TestWriteLadderSVGRoundTrip(t *testing.T) {
+
+	for _, filename := range []string{"./test/sidebar-312pt-mark-ladder.svg", "./test/textprefill.svg"} {
+
+		svgBytes, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, err := DefineLadderFromSVG(svgBytes)
+		if err != nil {
+			t.Fatalf("Error defining ladder %v", err)
+		}
+
+		var buf bytes.Buffer
+
+		err = WriteLadderSVG(&buf, want)
+		if err != nil {
+			t.Fatalf("Error writing ladder %v", err)
+		}
+
+		got, err := DefineLadderFromSVG(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: error reading back written ladder %v", filename, err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: ladder changed\n%v\n%v", filename, want, got)
+		}
+	}
+}
+
+func TestWriteLadderSVG(t *testing.T) {
+
+	max := float64(10)
+
+	// one mark box per question part, as a program would build it
+	want := &Ladder{
+		Anchor: geo.Point{X: 0, Y: 0},
+		Dim:    geo.Dim{Width: 60, Height: 100},
+		ID:     "generated & tweaked",
+		TextFields: []TextField{
+			TextField{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 10}, Dim: geo.Dim{Width: 20, Height: 15}}, ID: "q1a", Prefill: "0", TabSequence: 1},
+			TextField{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 30}, Dim: geo.Dim{Width: 20, Height: 15}}, ID: "q1b", Prefill: "0", TabSequence: 2,
+				Options: TextFieldOptions{Max: &max, Numeric: true, Align: "right"}},
+		},
+		TextPrefills: []TextPrefill{
+			TextPrefill{Rect: geo.Rect{Corner: geo.Point{X: 30, Y: 10}, Dim: geo.Dim{Width: 25, Height: 15}}, ID: "q1a-label",
+				Text: Paragraph{Text: "Part (a) <1>", TextSize: 8}},
+		},
+		Placeholders: []TextField{
+			TextField{Rect: geo.Rect{Corner: geo.Point{X: 30, Y: 30}, Dim: geo.Dim{Width: 25, Height: 15}}, ID: "part", Prefill: "1b", TabSequence: 3},
+		},
+		CheckBoxes: []CheckBox{
+			CheckBox{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 50}, Dim: geo.Dim{Width: 5, Height: 5}}, ID: "seen", TabSequence: 4,
+				Options: CheckBoxOptions{Checked: true, OnValue: "Yes"}},
+		},
+		DropDowns: []DropDown{
+			DropDown{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 60}, Dim: geo.Dim{Width: 20, Height: 5}}, ID: "grade", TabSequence: 5,
+				Options: ChoiceOptions{Choices: []string{"A", "B"}, Default: "B"}},
+		},
+		RadioGroups: []RadioGroup{
+			RadioGroup{ID: "q2", Default: "2", TabSequence: 6, Buttons: []RadioButton{
+				RadioButton{Rect: geo.Rect{Corner: geo.Point{X: 5, Y: 70}, Dim: geo.Dim{Width: 5, Height: 5}}, ID: "q2-radio-1", Value: "1", TabSequence: 6},
+				RadioButton{Rect: geo.Rect{Corner: geo.Point{X: 15, Y: 70}, Dim: geo.Dim{Width: 5, Height: 5}}, ID: "q2-radio-2", Value: "2", TabSequence: 7},
+			}},
+		},
+		Images: []ImageInsert{
+			ImageInsert{Data: []byte("hello"), Corner: geo.Point{X: 30, Y: 50}, Dim: geo.Dim{Width: 25, Height: 25}},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	err := WriteLadderSVG(&buf, want)
+	if err != nil {
+		t.Fatalf("Error writing ladder %v", err)
+	}
+
+	svg := buf.String()
+
+	for _, expected := range []string{
+		`inkscape:label="textfields"`,
+		`id="textfield-2-tab-2"`,
+		`<desc>{"align":"right","max":10,"numeric":true,"prefill":"0"}</desc>`,
+		`<title>ref-anchor</title>`,
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("Expected svg to contain %s\n%s", expected, svg)
+		}
+	}
+
+	got, err := DefineLadderFromSVG(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Error reading back written ladder %v", err)
+	}
+
+	// these are only filled in by parsing the descriptions
+	want.TextPrefills[0].Properties = `{"text":"Part (a) <1>","textSize":8}`
+	want.CheckBoxes[0].Properties = `{"checked":true,"onValue":"Yes"}`
+	want.DropDowns[0].Properties = `{"choices":["A","B"],"default":"B"}`
+	want.TextFields[1].Options.Prefill = "0"
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Ladder changed\n%v\n%v", want, got)
+	}
+}